@@ -0,0 +1,21 @@
+-- Уникальность order_uid по всем партициям для баз, созданных до появления
+-- order_uids в model.sql. Если дубликаты уже успели попасть в разные даты,
+-- регистрируется самый ранний заказ, остальные нужно разобрать вручную:
+--   SELECT order_uid FROM orders GROUP BY order_uid HAVING count(*) > 1;
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS order_uids (
+    order_uid TEXT PRIMARY KEY,
+    date_created TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_uids_date_created ON order_uids (date_created);
+
+INSERT INTO order_uids (order_uid, date_created)
+SELECT DISTINCT ON (order_uid) order_uid, date_created
+FROM orders
+ORDER BY order_uid, date_created
+ON CONFLICT (order_uid) DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,69 @@
+-- Перевод существующей непартиционированной схемы на model.sql.
+-- Месячные партиции под уже накопленные данные создаются здесь же,
+-- дальше их заранее создаёт `app maintain`.
+
+BEGIN;
+
+ALTER TABLE items RENAME TO items_old;
+ALTER TABLE payment RENAME TO payment_old;
+ALTER TABLE delivery RENAME TO delivery_old;
+ALTER TABLE orders RENAME TO orders_old;
+
+ALTER INDEX items_pkey RENAME TO items_old_pkey;
+ALTER INDEX payment_pkey RENAME TO payment_old_pkey;
+ALTER INDEX delivery_pkey RENAME TO delivery_old_pkey;
+ALTER INDEX orders_pkey RENAME TO orders_old_pkey;
+ALTER SEQUENCE items_id_seq RENAME TO items_old_id_seq;
+
+-- путь относительно этого файла, а не текущего каталога psql
+\ir model.sql
+
+DO $$
+DECLARE
+    m DATE;
+    t TEXT;
+BEGIN
+    FOR m IN
+        SELECT generate_series(
+            date_trunc('month', COALESCE(MIN(date_created), now()) AT TIME ZONE 'UTC'),
+            date_trunc('month', now() AT TIME ZONE 'UTC'),
+            interval '1 month'
+        )::date
+        FROM orders_old
+    LOOP
+        FOREACH t IN ARRAY ARRAY['orders', 'delivery', 'payment', 'items'] LOOP
+            EXECUTE format(
+                'CREATE TABLE IF NOT EXISTS %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L)',
+                t || '_' || to_char(m, '"y"YYYY"m"MM'), t,
+                m::timestamp AT TIME ZONE 'UTC',
+                (m + interval '1 month')::timestamp AT TIME ZONE 'UTC'
+            );
+        END LOOP;
+    END LOOP;
+END $$;
+
+INSERT INTO orders SELECT * FROM orders_old;
+
+INSERT INTO order_uids (order_uid, date_created)
+SELECT order_uid, date_created FROM orders_old;
+
+INSERT INTO delivery (order_uid, date_created, name, phone, zip, city, address, region, email)
+SELECT d.order_uid, o.date_created, d.name, d.phone, d.zip, d.city, d.address, d.region, d.email
+FROM delivery_old d JOIN orders_old o USING (order_uid);
+
+INSERT INTO payment (order_uid, date_created, transaction, request_id, currency, provider, amount,
+                     payment_dt, bank, delivery_cost, goods_total, custom_fee)
+SELECT p.order_uid, o.date_created, p.transaction, p.request_id, p.currency, p.provider, p.amount,
+       p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee
+FROM payment_old p JOIN orders_old o USING (order_uid);
+
+INSERT INTO items (order_uid, date_created, chrt_id, track_number, price, rid, name, sale, size,
+                   total_price, nm_id, brand, status)
+SELECT i.order_uid, o.date_created, i.chrt_id, i.track_number, i.price, i.rid, i.name, i.sale, i.size,
+       i.total_price, i.nm_id, i.brand, i.status
+FROM items_old i JOIN orders_old o USING (order_uid)
+ORDER BY i.id;
+
+DROP TABLE items_old, payment_old, delivery_old, orders_old;
+
+COMMIT;
@@ -0,0 +1,20 @@
+-- Колонки для шифрования ПДн доставки и исходных сообщений. Существующие строки
+-- delivery остаются в открытом виде (key_id IS NULL), их зашифрует фоновая задача
+-- сервиса; открытые raw_messages уйдут по сроку хранения.
+
+ALTER TABLE delivery
+    ADD COLUMN IF NOT EXISTS key_id TEXT,
+    ADD COLUMN IF NOT EXISTS wrapped_dek BYTEA,
+    ADD COLUMN IF NOT EXISTS phone_bidx TEXT,
+    ADD COLUMN IF NOT EXISTS email_bidx TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_delivery_phone_bidx ON delivery (phone_bidx);
+CREATE INDEX IF NOT EXISTS idx_delivery_email_bidx ON delivery (email_bidx);
+CREATE INDEX IF NOT EXISTS idx_delivery_key_id ON delivery (key_id);
+
+ALTER TABLE raw_messages
+    ADD COLUMN IF NOT EXISTS key_id TEXT,
+    ADD COLUMN IF NOT EXISTS wrapped_dek BYTEA;
+
+-- для ротации ключей: сообщения под выведенными ключами перешифровываются
+CREATE INDEX IF NOT EXISTS idx_raw_messages_key_id ON raw_messages (key_id);
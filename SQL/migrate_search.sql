@@ -0,0 +1,12 @@
+-- Полнотекстовый поиск по товарам и нечёткий поиск по имени получателя
+-- для баз, созданных до появления этих индексов в model.sql.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE items ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', brand), 'A') ||
+    setweight(to_tsvector('simple', name), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_items_search ON items USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_delivery_name_trgm ON delivery USING GIN (name gin_trgm_ops);
@@ -1,6 +1,11 @@
+-- Все таблицы партиционированы по date_created (помесячно).
+-- Партиции создаются заранее командой `app maintain`, старые отсоединяются
+-- и архивируются ею же. Первичный ключ партиционированной таблицы обязан
+-- содержать ключ партиционирования, поэтому уникальность order_uid
+-- по всем партициям держит отдельная таблица order_uids.
 
 CREATE TABLE orders (
-    order_uid TEXT PRIMARY KEY,
+    order_uid TEXT NOT NULL,
     track_number TEXT NOT NULL,
     entry TEXT NOT NULL,
     locale TEXT NOT NULL,
@@ -10,11 +15,23 @@ CREATE TABLE orders (
     shardkey TEXT NOT NULL,
     sm_id INTEGER NOT NULL,
     date_created TIMESTAMPTZ NOT NULL,
-    oof_shard TEXT NOT NULL
+    oof_shard TEXT NOT NULL,
+    PRIMARY KEY (order_uid, date_created)
+) PARTITION BY RANGE (date_created);
+
+CREATE INDEX idx_orders_order_uid ON orders (order_uid);
+
+-- uid регистрируется здесь в той же транзакции, что и вставка в orders
+CREATE TABLE order_uids (
+    order_uid TEXT PRIMARY KEY,
+    date_created TIMESTAMPTZ NOT NULL
 );
 
+CREATE INDEX idx_order_uids_date_created ON order_uids (date_created);
+
 CREATE TABLE delivery (
-    order_uid TEXT PRIMARY KEY,
+    order_uid TEXT NOT NULL,
+    date_created TIMESTAMPTZ NOT NULL,
     name TEXT NOT NULL,
     phone TEXT NOT NULL,
     zip TEXT NOT NULL,
@@ -22,14 +39,22 @@ CREATE TABLE delivery (
     address TEXT NOT NULL,
     region TEXT NOT NULL,
     email TEXT NOT NULL,
+    -- при включённом шифровании name, phone, email, address хранят шифртекст;
+    -- key_id IS NULL - строка ещё в открытом виде
+    key_id TEXT,
+    wrapped_dek BYTEA,
+    phone_bidx TEXT,
+    email_bidx TEXT,
+    PRIMARY KEY (order_uid, date_created),
     CONSTRAINT fk_delivery_order
-        FOREIGN KEY (order_uid)
-        REFERENCES orders(order_uid)
+        FOREIGN KEY (order_uid, date_created)
+        REFERENCES orders(order_uid, date_created)
         ON DELETE CASCADE
-);
+) PARTITION BY RANGE (date_created);
 
 CREATE TABLE payment (
-    order_uid TEXT PRIMARY KEY,
+    order_uid TEXT NOT NULL,
+    date_created TIMESTAMPTZ NOT NULL,
     transaction TEXT NOT NULL,
     request_id TEXT,
     currency TEXT NOT NULL,
@@ -40,15 +65,17 @@ CREATE TABLE payment (
     delivery_cost INTEGER NOT NULL,
     goods_total INTEGER NOT NULL,
     custom_fee INTEGER NOT NULL,
+    PRIMARY KEY (order_uid, date_created),
     CONSTRAINT fk_payment_order
-        FOREIGN KEY (order_uid)
-        REFERENCES orders(order_uid)
+        FOREIGN KEY (order_uid, date_created)
+        REFERENCES orders(order_uid, date_created)
         ON DELETE CASCADE
-);
+) PARTITION BY RANGE (date_created);
 
 CREATE TABLE items (
-    id SERIAL PRIMARY KEY,
+    id BIGSERIAL,
     order_uid TEXT NOT NULL,
+    date_created TIMESTAMPTZ NOT NULL,
     chrt_id INTEGER NOT NULL,
     track_number TEXT NOT NULL,
     price INTEGER NOT NULL,
@@ -60,8 +87,87 @@ CREATE TABLE items (
     nm_id INTEGER NOT NULL,
     brand TEXT NOT NULL,
     status INTEGER NOT NULL,
+    -- 'simple': названия и бренды смешивают русский и английский
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', brand), 'A') ||
+        setweight(to_tsvector('simple', name), 'B')
+    ) STORED,
+    PRIMARY KEY (id, date_created),
     CONSTRAINT fk_items_order
-        FOREIGN KEY (order_uid)
-        REFERENCES orders(order_uid)
+        FOREIGN KEY (order_uid, date_created)
+        REFERENCES orders(order_uid, date_created)
         ON DELETE CASCADE
+) PARTITION BY RANGE (date_created);
+
+CREATE INDEX idx_items_order_uid ON items (order_uid);
+CREATE INDEX idx_items_search ON items USING GIN (search_vector);
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX idx_delivery_name_trgm ON delivery USING GIN (name gin_trgm_ops);
+CREATE INDEX idx_delivery_phone_bidx ON delivery (phone_bidx);
+CREATE INDEX idx_delivery_email_bidx ON delivery (email_bidx);
+CREATE INDEX idx_delivery_key_id ON delivery (key_id);
+
+-- заказы вне созданных партиций
+CREATE TABLE orders_default PARTITION OF orders DEFAULT;
+CREATE TABLE delivery_default PARTITION OF delivery DEFAULT;
+CREATE TABLE payment_default PARTITION OF payment DEFAULT;
+CREATE TABLE items_default PARTITION OF items DEFAULT;
+
+-- текущий и следующий месяц сразу: партицию нельзя создать, пока в DEFAULT
+-- лежат строки из её диапазона, а до первого `app maintain` пишутся заказы
+DO $$
+DECLARE
+    m DATE;
+    t TEXT;
+BEGIN
+    FOR m IN
+        SELECT generate_series(
+            date_trunc('month', now() AT TIME ZONE 'UTC'),
+            date_trunc('month', now() AT TIME ZONE 'UTC') + interval '1 month',
+            interval '1 month'
+        )::date
+    LOOP
+        FOREACH t IN ARRAY ARRAY['orders', 'delivery', 'payment', 'items'] LOOP
+            EXECUTE format(
+                'CREATE TABLE IF NOT EXISTS %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L)',
+                t || '_' || to_char(m, '"y"YYYY"m"MM'), t,
+                m::timestamp AT TIME ZONE 'UTC',
+                (m + interval '1 month')::timestamp AT TIME ZONE 'UTC'
+            );
+        END LOOP;
+    END LOOP;
+END $$;
+
+-- исходные сообщения из Kafka, как они были получены
+CREATE TABLE IF NOT EXISTS raw_messages (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid TEXT,
+    topic TEXT NOT NULL,
+    kafka_partition INTEGER NOT NULL,
+    kafka_offset BIGINT NOT NULL,
+    key BYTEA,
+    headers JSONB NOT NULL DEFAULT '[]',
+    value BYTEA NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL,
+    -- при включённом шифровании value хранит шифртекст, как и ПДн в delivery
+    key_id TEXT,
+    wrapped_dek BYTEA
+);
+
+CREATE INDEX IF NOT EXISTS idx_raw_messages_order_uid ON raw_messages (order_uid);
+CREATE INDEX IF NOT EXISTS idx_raw_messages_received_at ON raw_messages (received_at);
+CREATE INDEX IF NOT EXISTS idx_raw_messages_key_id ON raw_messages (key_id);
+
+-- запросы субъектов персональных данных (выгрузка, обезличивание)
+CREATE TABLE IF NOT EXISTS data_requests (
+    id BIGSERIAL PRIMARY KEY,
+    customer_id TEXT NOT NULL,
+    request_type TEXT NOT NULL CHECK (request_type IN ('export', 'erase')),
+    requested_by TEXT NOT NULL,
+    orders_affected INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
 );
+
+CREATE INDEX IF NOT EXISTS idx_data_requests_customer_id ON data_requests (customer_id);
+CREATE INDEX idx_orders_customer_id ON orders (customer_id);
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/torrentxok/order_service/internal/cache"
+	"github.com/torrentxok/order_service/internal/config"
+	"github.com/torrentxok/order_service/internal/pii"
+	"github.com/torrentxok/order_service/internal/service"
+	"go.uber.org/zap"
+)
+
+func newCache(cfg config.CacheConfig) (cache.OrderCache, error) {
+	opts := []cache.Option{cache.WithTTL(cfg.TTL), cache.WithMaxBytes(cfg.MaxBytes)}
+
+	if cfg.Shards > 1 {
+		return cache.NewShardedCache(cfg.Policy, cfg.Size, cfg.Shards, opts...)
+	}
+	return cache.New(cfg.Policy, cfg.Size, opts...)
+}
+
+func newRedisCache(cfg config.RedisConfig, cipher *pii.Cipher) *cache.RedisCache {
+	client := redis.NewClient(&redis.Options{
+		Addr:         cfg.Addr,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		DialTimeout:  cfg.Timeout,
+		ReadTimeout:  cfg.Timeout,
+		WriteTimeout: cfg.Timeout,
+		PoolTimeout:  cfg.Timeout,
+	})
+
+	return cache.NewRedisCache(client, cipher, cfg.KeyPrefix, cfg.TTL, cfg.Timeout)
+}
+
+// warmUpCache поднимает кэш из снимка, а если его нет или он не подходит - из БД
+func warmUpCache(ctx context.Context, cfg config.CacheConfig, c cache.OrderCache, cipher *pii.Cipher, orders *service.OrderService, log *zap.Logger) {
+	if cfg.SnapshotFile != "" {
+		n, err := cache.LoadSnapshot(cfg.SnapshotFile, c, cfg.SnapshotMaxAge, cipher)
+		switch {
+		case err == nil && n > 0:
+			log.Info("cache restored from snapshot", zap.Int("count", n), zap.String("file", cfg.SnapshotFile))
+			return
+		case err == nil, errors.Is(err, os.ErrNotExist):
+			log.Info("no cache snapshot, warming up from db", zap.String("file", cfg.SnapshotFile))
+		default:
+			log.Warn("cache snapshot rejected, warming up from db", zap.String("file", cfg.SnapshotFile), zap.Error(err))
+		}
+	}
+
+	if err := orders.WarmUpCache(ctx); err != nil {
+		log.Warn("failed to warm up cache", zap.Error(err))
+	}
+}
+
+// saveCacheSnapshot шифрует снимок, если настроено шифрование ПДн
+func saveCacheSnapshot(cfg config.CacheConfig, c cache.OrderCache, cipher *pii.Cipher, log *zap.Logger) {
+	if cfg.SnapshotFile == "" {
+		return
+	}
+
+	n, err := cache.SaveSnapshot(cfg.SnapshotFile, c, cipher)
+	if err != nil {
+		log.Error("failed to save cache snapshot", zap.String("file", cfg.SnapshotFile), zap.Error(err))
+		return
+	}
+	log.Info("cache snapshot saved", zap.Int("count", n), zap.String("file", cfg.SnapshotFile))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/torrentxok/order_service/internal/config"
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// check ищет нарушения целостности заказов и пишет их в stdout как NDJSON.
+// С -repair удаляет неполные заказы, чтобы их можно было принять из Kafka заново;
+// расхождения в данных (track_number, goods_total) только показываются.
+func check(ctx context.Context, cfg *config.Config, log *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	batch := fs.Int("batch", 500, "orders per DB round trip")
+	repair := fs.Bool("repair", false, "delete incomplete orders")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	repo, err := openBulkRepository(ctx, cfg, log)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	enc := json.NewEncoder(os.Stdout)
+	counts := make(map[string]int)
+	var found, repaired int
+
+	err = repo.CheckConsistency(ctx, *batch, func(issues []models.Inconsistency) error {
+		deleted := make(map[string]bool)
+
+		for _, issue := range issues {
+			if *repair && issue.Incomplete() {
+				if !deleted[issue.OrderUID] {
+					err := repo.DeleteOrder(ctx, issue.OrderUID)
+					if err != nil && !errors.Is(err, repository.ErrOrderNotFound) {
+						return err
+					}
+					deleted[issue.OrderUID] = true
+					repaired++
+				}
+				issue.Repaired = true
+			}
+
+			found++
+			counts[issue.Kind]++
+			if err := enc.Encode(issue); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fields := []zap.Field{zap.Int("issues", found), zap.Int("orders_deleted", repaired)}
+	for kind, n := range counts {
+		fields = append(fields, zap.Int(kind, n))
+	}
+	log.Info("consistency check finished", fields...)
+
+	return nil
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	kafkago "github.com/segmentio/kafka-go"
 	"github.com/torrentxok/order_service/internal/cache"
 	"github.com/torrentxok/order_service/internal/config"
@@ -14,6 +16,7 @@ import (
 	"github.com/torrentxok/order_service/internal/http/handler"
 	kafkaConsumer "github.com/torrentxok/order_service/internal/kafka"
 	"github.com/torrentxok/order_service/internal/logger"
+	"github.com/torrentxok/order_service/internal/metrics"
 	"github.com/torrentxok/order_service/internal/repository"
 	"github.com/torrentxok/order_service/internal/service"
 	"go.uber.org/zap"
@@ -38,21 +41,133 @@ func main() {
 	}
 	defer log.Sync()
 
+	command := "serve"
+	args := []string{}
+	if len(os.Args) > 1 {
+		command, args = os.Args[1], os.Args[2:]
+	}
+
+	switch command {
+	case "serve":
+		serve(ctx, cfg, log)
+	case "maintain":
+		err = maintain(ctx, cfg, log, args)
+	case "export":
+		err = export(ctx, cfg, log, args)
+	case "import":
+		err = importOrders(ctx, cfg, log, args)
+	case "check":
+		err = check(ctx, cfg, log, args)
+	case "cache-replay":
+		err = cacheReplay(ctx, cfg, log, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", command)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal("command failed", zap.String("command", command), zap.Error(err))
+	}
+}
+
+func serve(ctx context.Context, cfg *config.Config, log *zap.Logger) {
 	log.Info("service starting")
 
-	db, err := repository.NewRepository(cfg.DB, log)
+	db, err := repository.NewRepository(ctx, cfg.DB, log)
 	if err != nil {
 		log.Fatal("failed to connect to db", zap.Error(err))
 	}
 	defer db.Close()
 
-	orderCache := cache.NewLRUCache(cfg.Cache.Size)
+	if err := db.RegisterMetrics(prometheus.DefaultRegisterer, "primary"); err != nil {
+		log.Warn("failed to register db metrics", zap.Error(err))
+	}
+
+	cipher, err := newCipher(cfg.PII)
+	if err != nil {
+		log.Fatal("failed to load PII keys", zap.Error(err))
+	}
+	if cipher != nil {
+		db.EnableEncryption(cipher)
+	}
+
+	// заказы - в шардах, если они настроены; сырые сообщения остаются в основной БД,
+	// а аналитика и поиск шарды не опрашивают и при шардировании выключаются
+	var (
+		orders    repository.OrderRepository        = db
+		bulk      repository.OrderBulkRepository    = db
+		customers repository.CustomerDataRepository = db
+		notifier  repository.ChangeNotifier         = repository.NewPgNotifier(cfg.DB, log)
+		publisher repository.ChangePublisher        = db
+
+		reencryptDeliveries service.ReencryptFunc = db.ReencryptDeliveries
+	)
+
+	if len(cfg.Shard.Shards) > 0 {
+		sharded, err := repository.NewShardedRepository(ctx, cfg.DB, cfg.Shard, log)
+		if err != nil {
+			log.Fatal("failed to connect to shards", zap.Error(err))
+		}
+		defer sharded.Close()
+
+		if err := sharded.RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+			log.Warn("failed to register shard metrics", zap.Error(err))
+		}
+
+		if cipher != nil {
+			sharded.EnableEncryption(cipher)
+		}
+
+		orders, bulk, customers, notifier, publisher = sharded, sharded, sharded, sharded, sharded
+		reencryptDeliveries = sharded.ReencryptDeliveries
+		log.Info("sharded storage enabled", zap.Int("shards", len(cfg.Shard.Shards)))
+	}
+
+	if cipher != nil {
+		log.Info("delivery PII encryption enabled", zap.String("key_id", cipher.CurrentKeyID()))
+		go service.RunReencryption(ctx, "delivery", reencryptDeliveries, cfg.PII.ReencryptInterval, cfg.PII.ReencryptBatch, log)
+		// сырые сообщения всегда в основной БД
+		go service.RunReencryption(ctx, "raw_messages", db.ReencryptRawMessages, cfg.PII.ReencryptInterval, cfg.PII.ReencryptBatch, log)
+	}
+
+	orderCache, err := newCache(cfg.Cache)
+	if err != nil {
+		log.Fatal("failed to create cache", zap.Error(err))
+	}
+	if cfg.Cache.TTL > 0 {
+		go cache.RunJanitor(ctx, orderCache, cfg.Cache.JanitorInterval)
+	}
+	if err := metrics.RegisterCache(prometheus.DefaultRegisterer, "local", orderCache); err != nil {
+		log.Warn("failed to register cache metrics", zap.Error(err))
+	}
+
+	if cfg.Redis.Addr != "" {
+		l2 := newRedisCache(cfg.Redis, cipher)
+		defer l2.Close()
+
+		if err := metrics.RegisterCache(prometheus.DefaultRegisterer, "redis", l2); err != nil {
+			log.Warn("failed to register redis cache metrics", zap.Error(err))
+		}
+
+		orderCache = cache.NewTieredCache(orderCache, l2)
+		log.Info("redis L2 cache enabled", zap.String("addr", cfg.Redis.Addr))
+	}
+	if cfg.Cache.StatsInterval > 0 {
+		go service.RunCacheStatsLog(ctx, orderCache, cfg.Cache.StatsInterval, log)
+	}
+
+	orderService := service.NewOrderService(orders, orderCache, log)
+
+	warmUpCache(ctx, cfg.Cache, orderCache, cipher, orderService, log)
 
-	orderService := service.NewOrderService(db, orderCache, log)
+	invalidator := service.NewCacheInvalidator(notifier, orders, orderCache, log)
 
-	if err := orderService.WarmUpCache(ctx); err != nil {
-		log.Warn("failed to warm up cache", zap.Error(err))
+	if cfg.Cache.NegativeSize > 0 {
+		negative := cache.NewNegativeCache(cfg.Cache.NegativeSize, cfg.Cache.NegativeTTL)
+		orderService.EnableNegativeCache(negative)
+		invalidator.EnableNegativeCache(negative)
 	}
+	go invalidator.Run(ctx)
 
 	kafkaReader := kafkago.NewReader(kafkago.ReaderConfig{
 		Brokers: cfg.Kafka.Brokers,
@@ -61,7 +176,10 @@ func main() {
 	})
 	defer kafkaReader.Close()
 
-	consumer := kafkaConsumer.NewConsumer(kafkaReader, orderService, log)
+	auditService := service.NewAuditService(db, cfg.Audit.Retention, log)
+	go auditService.RunCleanup(ctx, time.Hour)
+
+	consumer := kafkaConsumer.NewConsumer(kafkaReader, orderService, auditService, log)
 
 	go func() {
 		if err := consumer.Run(ctx); err != nil {
@@ -69,13 +187,29 @@ func main() {
 		}
 	}()
 
-	orderHandler := handler.NewOrderHandler(orderService, log)
+	gdprService := service.NewGDPRService(bulk, customers, db, orderCache, log)
 
-	httpServer := http.NewServer(
-		":"+cfg.Server.Port,
-		orderHandler,
-		log,
-	)
+	cacheAdmin := service.NewCacheAdminService(orderCache, orderService, log)
+	cacheAdmin.EnableBroadcast(publisher)
+
+	handlers := http.Handlers{
+		Order: handler.NewOrderHandler(orderService, log),
+		Admin: handler.NewAdminHandler(auditService, gdprService, log),
+		Cache: handler.NewCacheHandler(cacheAdmin, log),
+	}
+
+	// по одной основной БД они вернули бы неполные данные
+	if len(cfg.Shard.Shards) == 0 {
+		statsService := service.NewStatsService(repository.NewAnalyticsRepository(db, log), log)
+		searchService := service.NewSearchService(db, log)
+
+		handlers.Stats = handler.NewStatsHandler(statsService, log)
+		handlers.Search = handler.NewSearchHandler(searchService, log)
+	} else {
+		log.Warn("stats and search do not support sharded storage, endpoints disabled")
+	}
+
+	httpServer := http.NewServer(":"+cfg.Server.Port, handlers, cfg.Server.AdminToken, log)
 
 	httpServer.Start()
 
@@ -90,5 +224,7 @@ func main() {
 		log.Error("http shutdown error", zap.Error(err))
 	}
 
+	saveCacheSnapshot(cfg.Cache, orderCache, cipher, log)
+
 	log.Info("service stopped gracefully")
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+
+	"github.com/torrentxok/order_service/internal/config"
+	"github.com/torrentxok/order_service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// maintain создаёт партиции заранее и применяет политику хранения.
+// Рассчитан на запуск по расписанию (cron, k8s CronJob).
+// При шардировании обслуживаются и основная БД, и все шарды.
+func maintain(ctx context.Context, cfg *config.Config, log *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("maintain", flag.ContinueOnError)
+	fs.IntVar(&cfg.Partition.Premake, "premake", cfg.Partition.Premake, "months of partitions to create ahead")
+	fs.IntVar(&cfg.Partition.RetentionMonths, "retention", cfg.Partition.RetentionMonths, "archive partitions older than N months, 0 keeps everything")
+	fs.StringVar(&cfg.Partition.ArchiveDir, "archive-dir", cfg.Partition.ArchiveDir, "directory for exported partitions")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := repository.NewRepository(ctx, cfg.DB, log)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	primaryErr := repository.NewPartitionManager(db, cfg.Partition, log).Run(ctx)
+
+	// заказы при шардировании лежат в шардах, у каждого свои партиции
+	if len(cfg.Shard.Shards) == 0 {
+		return primaryErr
+	}
+
+	sharded, err := repository.NewShardedRepository(ctx, cfg.DB, cfg.Shard, log)
+	if err != nil {
+		return errors.Join(primaryErr, err)
+	}
+	defer sharded.Close()
+
+	return errors.Join(primaryErr, sharded.MaintainPartitions(ctx, cfg.Partition))
+}
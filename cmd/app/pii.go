@@ -0,0 +1,20 @@
+package main
+
+import (
+	"github.com/torrentxok/order_service/internal/config"
+	"github.com/torrentxok/order_service/internal/pii"
+)
+
+// newCipher возвращает nil, если шифрование ПДн не настроено
+func newCipher(cfg config.PIIConfig) (*pii.Cipher, error) {
+	if cfg.KeyFile == "" {
+		return nil, nil
+	}
+
+	keys, err := pii.LoadFileKeyProvider(cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return pii.NewCipher(keys), nil
+}
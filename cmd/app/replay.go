@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/torrentxok/order_service/internal/cache"
+	"github.com/torrentxok/order_service/internal/config"
+	"github.com/torrentxok/order_service/internal/models"
+	"go.uber.org/zap"
+)
+
+// cacheReplay прогоняет записанный поток обращений через каждую политику кэша
+// и печатает hit ratio. Трасса - по ключу на строку либо access log сервиса:
+// из строк с "/order/<uid>" берётся uid. Промах моделирует загрузку из БД через Set.
+func cacheReplay(ctx context.Context, cfg *config.Config, log *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("cache-replay", flag.ContinueOnError)
+	trace := fs.String("trace", "-", "trace file, - for stdin")
+	size := fs.Int("size", cfg.Cache.Size, "cache capacity")
+	policies := fs.String("policies", strings.Join([]string{
+		cache.PolicyLRU, cache.PolicyLFU, cache.PolicyARC, cache.PolicyTinyLFU,
+	}, ","), "comma-separated policies to compare")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	in := io.Reader(os.Stdin)
+	if *trace != "-" {
+		f, err := os.Open(*trace)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	keys, err := readTrace(in)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return errors.New("trace contains no keys")
+	}
+	log.Info("trace loaded", zap.Int("requests", len(keys)))
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "policy\trequests\thits\tmisses\tevictions\thit ratio\t")
+
+	for _, policy := range strings.Split(*policies, ",") {
+		c, err := cache.New(strings.TrimSpace(policy), *size)
+		if err != nil {
+			return err
+		}
+
+		placeholder := &models.Order{}
+		for i, key := range keys {
+			if i%10000 == 0 && ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if _, ok := c.Get(key); !ok {
+				c.Set(key, placeholder)
+			}
+		}
+
+		st := c.Stats()
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%.4f\t\n",
+			policy, len(keys), st.Hits, st.Misses, st.Evictions, st.HitRatio())
+	}
+
+	return tw.Flush()
+}
+
+func readTrace(r io.Reader) ([]string, error) {
+	var keys []string
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		if key := traceKey(sc.Text()); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, sc.Err()
+}
+
+func traceKey(line string) string {
+	if _, rest, ok := strings.Cut(line, "/order/"); ok {
+		end := strings.IndexAny(rest, " \"?/")
+		if end >= 0 {
+			rest = rest[:end]
+		}
+		return rest
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/config"
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/repository"
+	"github.com/torrentxok/order_service/internal/transfer"
+	"go.uber.org/zap"
+)
+
+type bulkRepository interface {
+	repository.OrderBulkRepository
+	repository.ConsistencyChecker
+	Close() error
+}
+
+// openBulkRepository учитывает шардирование так же, как serve
+func openBulkRepository(ctx context.Context, cfg *config.Config, log *zap.Logger) (bulkRepository, error) {
+	cipher, err := newCipher(cfg.PII)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Shard.Shards) > 0 {
+		repo, err := repository.NewShardedRepository(ctx, cfg.DB, cfg.Shard, log)
+		if err != nil {
+			return nil, err
+		}
+		if cipher != nil {
+			repo.EnableEncryption(cipher)
+		}
+		return repo, nil
+	}
+
+	repo, err := repository.NewRepository(ctx, cfg.DB, log)
+	if err != nil {
+		return nil, err
+	}
+	if cipher != nil {
+		repo.EnableEncryption(cipher)
+	}
+	return repo, nil
+}
+
+// export выгружает заказы: app export -format csv -from 2024-01-01 -out orders.csv
+func export(ctx context.Context, cfg *config.Config, log *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", transfer.FormatNDJSON, "ndjson, csv or parquet")
+	out := fs.String("out", "-", "output file, - for stdout")
+	from := fs.String("from", "", "date_created lower bound, RFC3339 or YYYY-MM-DD")
+	to := fs.String("to", "", "date_created upper bound (exclusive)")
+	customer := fs.String("customer", "", "customer_id")
+	batch := fs.Int("batch", 500, "orders per DB round trip")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var filter models.OrderFilter
+	var err error
+	if filter.From, err = parseDateFlag(*from); err != nil {
+		return fmt.Errorf("-from: %w", err)
+	}
+	if filter.To, err = parseDateFlag(*to); err != nil {
+		return fmt.Errorf("-to: %w", err)
+	}
+	filter.CustomerID = *customer
+
+	repo, err := openBulkRepository(ctx, cfg, log)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	var dst io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		dst = f
+	}
+
+	w, err := transfer.NewWriter(*format, dst)
+	if err != nil {
+		return err
+	}
+
+	exported := 0
+	err = repo.ScanOrders(ctx, filter, *batch, func(orders []*models.Order) error {
+		for _, o := range orders {
+			if err := w.Write(o); err != nil {
+				return err
+			}
+		}
+		exported += len(orders)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	log.Info("export finished", zap.Int("orders", exported), zap.String("format", *format))
+	return nil
+}
+
+// importOrders загружает заказы: app import -format ndjson -file orders.ndjson -dry-run
+func importOrders(ctx context.Context, cfg *config.Config, log *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", transfer.FormatNDJSON, "ndjson or csv")
+	file := fs.String("file", "-", "input file, - for stdin")
+	batchSize := fs.Int("batch", 500, "orders per transaction")
+	dryRun := fs.Bool("dry-run", false, "validate only, write nothing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var src io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src = f
+	}
+
+	r, err := transfer.NewReader(*format, src)
+	if err != nil {
+		return err
+	}
+
+	var repo bulkRepository
+	if !*dryRun {
+		if repo, err = openBulkRepository(ctx, cfg, log); err != nil {
+			return err
+		}
+		defer repo.Close()
+	}
+
+	var read, invalid, valid, created int
+	batch := make([]*models.Order, 0, *batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 || *dryRun {
+			batch = batch[:0]
+			return nil
+		}
+
+		n, err := repo.CreateOrders(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		created += n
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		o, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var recErr *transfer.RecordError
+		if errors.As(err, &recErr) {
+			invalid++
+			log.Warn("skipping unreadable record", zap.Error(err))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		read++
+
+		if err := o.Validate(); err != nil {
+			invalid++
+			log.Warn("skipping invalid order", zap.String("order_uid", o.OrderUID), zap.Error(err))
+			continue
+		}
+
+		valid++
+		batch = append(batch, o)
+		if len(batch) == *batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	log.Info("import finished",
+		zap.Bool("dry_run", *dryRun),
+		zap.Int("read", read),
+		zap.Int("invalid", invalid),
+		zap.Int("valid", valid),
+		zap.Int("created", created),
+	)
+	return nil
+}
+
+func parseDateFlag(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
@@ -0,0 +1,159 @@
+package cache
+
+import "container/list"
+
+// arcPolicy - Adaptive Replacement Cache (Megiddo, Modha).
+// t1 - ключи, встреченные один раз, t2 - повторно; b1, b2 - их «призраки»
+// без значений. Попадание в призрак сдвигает целевой размер t1 (p)
+// в сторону свежести или частоты, поэтому длинный скан холодных
+// заказов вытесняет только t1 и не трогает горячие ключи в t2.
+type arcPolicy struct {
+	capacity int
+	p        int
+
+	t1, t2, b1, b2 *list.List
+	nodes          map[string]*arcNode
+}
+
+type arcNode struct {
+	list *list.List
+	elem *list.Element
+}
+
+func newARCPolicy(capacity int) *arcPolicy {
+	return &arcPolicy{
+		capacity: capacity,
+		t1:       list.New(),
+		t2:       list.New(),
+		b1:       list.New(),
+		b2:       list.New(),
+		nodes:    make(map[string]*arcNode),
+	}
+}
+
+func (p *arcPolicy) add(key string, _ int) []string {
+	c := p.capacity
+	var evicted []string
+
+	if node, ok := p.nodes[key]; ok {
+		switch node.list {
+		case p.b1:
+			p.p = min(c, p.p+max(p.b2.Len()/p.b1.Len(), 1))
+			evicted = p.replace(false)
+			p.move(key, p.t2)
+			return evicted
+		case p.b2:
+			p.p = max(0, p.p-max(p.b1.Len()/p.b2.Len(), 1))
+			evicted = p.replace(true)
+			p.move(key, p.t2)
+			return evicted
+		default:
+			// уже в кэше: policyCache так не вызывает, но состояние не ломаем
+			p.touch(key)
+			return nil
+		}
+	}
+
+	if l1 := p.t1.Len() + p.b1.Len(); l1 >= c {
+		if p.t1.Len() < c {
+			p.dropLRU(p.b1)
+			evicted = p.replace(false)
+		} else {
+			evicted = append(evicted, p.dropLRU(p.t1))
+		}
+	} else if total := l1 + p.t2.Len() + p.b2.Len(); total >= c {
+		if total >= 2*c {
+			p.dropLRU(p.b2)
+		}
+		evicted = p.replace(false)
+	}
+
+	p.nodes[key] = &arcNode{list: p.t1, elem: p.t1.PushFront(key)}
+	return evicted
+}
+
+func (p *arcPolicy) touch(key string) {
+	if node, ok := p.nodes[key]; ok && (node.list == p.t1 || node.list == p.t2) {
+		p.move(key, p.t2)
+	}
+}
+
+func (p *arcPolicy) remove(key string) {
+	if node, ok := p.nodes[key]; ok {
+		node.list.Remove(node.elem)
+		delete(p.nodes, key)
+	}
+}
+
+// evict - как replace, но без условия на заполненность: места не хватает по объёму
+func (p *arcPolicy) evict() (string, bool) {
+	var key string
+	switch {
+	case p.t1.Len() > 0 && (p.t1.Len() > p.p || p.t2.Len() == 0):
+		key = p.demote(p.t1, p.b1)
+	case p.t2.Len() > 0:
+		key = p.demote(p.t2, p.b2)
+	default:
+		return "", false
+	}
+
+	p.trimGhosts()
+	return key, true
+}
+
+// trimGhosts возвращает призраки в границы ARC: |t1|+|b1| <= c и всего не больше 2c.
+// В add их держит сам алгоритм, а вытеснение по объёму только пополняет призраки.
+func (p *arcPolicy) trimGhosts() {
+	c := p.capacity
+	for p.b1.Len() > 0 && p.t1.Len()+p.b1.Len() > c {
+		p.dropLRU(p.b1)
+	}
+	for p.b1.Len()+p.b2.Len() > 0 && p.t1.Len()+p.t2.Len()+p.b1.Len()+p.b2.Len() > 2*c {
+		if p.b2.Len() > 0 {
+			p.dropLRU(p.b2)
+		} else {
+			p.dropLRU(p.b1)
+		}
+	}
+}
+
+// replace освобождает место в t1+t2, перенося LRU-ключ в соответствующий призрак.
+// После явных Delete кэш может быть неполным - тогда вытеснять некого и незачем.
+func (p *arcPolicy) replace(inB2 bool) []string {
+	if p.t1.Len()+p.t2.Len() < p.capacity {
+		return nil
+	}
+
+	if p.t1.Len() > 0 && (p.t1.Len() > p.p || (inB2 && p.t1.Len() == p.p)) {
+		return []string{p.demote(p.t1, p.b1)}
+	}
+	if p.t2.Len() > 0 {
+		return []string{p.demote(p.t2, p.b2)}
+	}
+	return []string{p.demote(p.t1, p.b1)}
+}
+
+func (p *arcPolicy) demote(from, to *list.List) string {
+	key := from.Back().Value.(string)
+	p.move(key, to)
+	return key
+}
+
+func (p *arcPolicy) move(key string, to *list.List) {
+	node := p.nodes[key]
+	node.list.Remove(node.elem)
+	node.list = to
+	node.elem = to.PushFront(key)
+}
+
+func (p *arcPolicy) dropLRU(l *list.List) string {
+	elem := l.Back()
+	if elem == nil {
+		return ""
+	}
+
+	key := elem.Value.(string)
+	l.Remove(elem)
+	delete(p.nodes, key)
+	return key
+}
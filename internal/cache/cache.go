@@ -1,10 +1,181 @@
 package cache
 
-import "github.com/torrentxok/order_service/internal/models"
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/models"
+)
 
 type OrderCache interface {
 	Get(key string) (*models.Order, bool)
+	// Set кладёт значение с TTL кэша по умолчанию
 	Set(key string, value *models.Order)
+	// SetWithTTL переопределяет TTL для одной записи, ttl <= 0 - без срока
+	SetWithTTL(key string, value *models.Order, ttl time.Duration)
 	Delete(key string)
 	Capacity() int
+	Stats() Stats
+	// DeleteExpired удаляет истёкшие записи и возвращает их число
+	DeleteExpired() int
+	// Entries - копия содержимого от самых востребованных записей к менее;
+	// порядок точный для LRU, для остальных политик - произвольный
+	Entries() []Entry
+	// Peek возвращает запись без учёта в статистике и порядке вытеснения
+	Peek(key string) (Entry, bool)
+	// Purge удаляет все записи, счётчики обращений сохраняются
+	Purge()
+}
+
+// Resizer - кэш, ёмкость которого можно менять на ходу
+type Resizer interface {
+	Resize(capacity int) error
+}
+
+var ErrResizeUnsupported = errors.New("cache does not support resizing")
+
+type Entry struct {
+	Key      string        `json:"key"`
+	Value    *models.Order `json:"value"`
+	StoredAt time.Time     `json:"stored_at"`
+	// нулевое - без срока
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Stats - счётчики с момента создания кэша; Len - текущее число записей
+type Stats struct {
+	Hits        uint64 `json:"hits"`
+	Misses      uint64 `json:"misses"`
+	Evictions   uint64 `json:"evictions"`
+	Expirations uint64 `json:"expirations"`
+	Len         int    `json:"len"`
+	Capacity    int    `json:"capacity"`
+	// оценка занятой памяти, см. EstimateSize; MaxBytes 0 - без ограничения
+	Bytes    int64 `json:"bytes"`
+	MaxBytes int64 `json:"max_bytes"`
+	// ошибки внешнего хранилища (L2), для локальных кэшей 0
+	Errors uint64 `json:"errors"`
+}
+
+// HitRatio - доля попаданий, 0 если обращений не было
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// Clock подменяется в тестах, чтобы проверять истечение без sleep
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// RunJanitor периодически вычищает истёкшие записи, которые никто не читает
+func RunJanitor(ctx context.Context, c OrderCache, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.DeleteExpired()
+		}
+	}
+}
+
+type options struct {
+	ttl      time.Duration
+	maxBytes int64
+	clock    Clock
+}
+
+type Option func(*options)
+
+// WithTTL задаёт срок жизни записей по умолчанию, 0 - без срока
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) { o.ttl = ttl }
+}
+
+// WithMaxBytes ограничивает кэш оценкой объёма записей вдобавок к числу записей
+func WithMaxBytes(n int64) Option {
+	return func(o *options) { o.maxBytes = n }
+}
+
+func WithClock(clock Clock) Option {
+	return func(o *options) { o.clock = clock }
+}
+
+func newOptions(opts []Option) options {
+	o := options{clock: realClock{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+type entry struct {
+	key   string
+	value *models.Order
+	size  int64
+	// нулевое - без срока
+	expiresAt time.Time
+	storedAt  time.Time
+}
+
+func (e *entry) export() Entry {
+	return Entry{Key: e.key, Value: e.value, StoredAt: e.storedAt, ExpiresAt: e.expiresAt}
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+func (o options) expiresAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return o.clock.Now().Add(ttl)
+}
+
+// counters читаются в Stats без блокировки кэша
+type counters struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+	length      atomic.Int64
+	bytes       atomic.Int64
+}
+
+func (c *counters) stats(capacity int, maxBytes int64) Stats {
+	return Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+		Len:         int(c.length.Load()),
+		Capacity:    capacity,
+		Bytes:       c.bytes.Load(),
+		MaxBytes:    maxBytes,
+	}
+}
+
+// tooLarge - запись больше всего бюджета: ради неё пришлось бы вытеснить весь кэш,
+// и она всё равно не поместилась бы
+func (o options) tooLarge(size int64) bool {
+	return o.maxBytes > 0 && size > o.maxBytes
+}
+
+// overBudget - нужно ли вытеснять ещё
+func (o options) overBudget(length, capacity int, bytes int64) bool {
+	return length > capacity || (o.maxBytes > 0 && bytes > o.maxBytes)
 }
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/models"
+)
+
+// fakeClock - ручные часы для проверок TTL
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func testOrder(uid string) *models.Order {
+	return &models.Order{OrderUID: uid}
+}
+
+func TestPeekReportsStoredAt(t *testing.T) {
+	for _, policy := range []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU} {
+		t.Run(policy, func(t *testing.T) {
+			clock := newFakeClock()
+			c, err := New(policy, 10, WithClock(clock))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c.Set("a", testOrder("a"))
+			e, ok := c.Peek("a")
+			if !ok {
+				t.Fatal("Peek: entry not found")
+			}
+			if !e.StoredAt.Equal(clock.Now()) {
+				t.Fatalf("StoredAt = %v, want %v", e.StoredAt, clock.Now())
+			}
+
+			// перезапись обновляет время
+			clock.Advance(time.Minute)
+			c.Set("a", testOrder("a"))
+			e, _ = c.Peek("a")
+			if !e.StoredAt.Equal(clock.Now()) {
+				t.Fatalf("StoredAt after update = %v, want %v", e.StoredAt, clock.Now())
+			}
+		})
+	}
+}
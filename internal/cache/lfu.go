@@ -0,0 +1,101 @@
+package cache
+
+import "container/heap"
+
+// lfuPolicy вытесняет ключ с наименьшим числом обращений,
+// при равенстве - тот, к которому дольше всего не обращались
+type lfuPolicy struct {
+	heap  lfuHeap
+	nodes map[string]*lfuNode
+	tick  uint64
+}
+
+type lfuNode struct {
+	key   string
+	freq  uint64
+	tick  uint64
+	index int
+}
+
+func newLFUPolicy() *lfuPolicy {
+	return &lfuPolicy{nodes: make(map[string]*lfuNode)}
+}
+
+func (p *lfuPolicy) add(key string, capacity int) []string {
+	var evicted []string
+	for len(p.heap) >= capacity {
+		victim := heap.Pop(&p.heap).(*lfuNode)
+		delete(p.nodes, victim.key)
+		evicted = append(evicted, victim.key)
+	}
+
+	p.tick++
+	node := &lfuNode{key: key, freq: 1, tick: p.tick}
+	p.nodes[key] = node
+	heap.Push(&p.heap, node)
+
+	return evicted
+}
+
+func (p *lfuPolicy) touch(key string) {
+	node, ok := p.nodes[key]
+	if !ok {
+		return
+	}
+
+	p.tick++
+	node.freq++
+	node.tick = p.tick
+	heap.Fix(&p.heap, node.index)
+}
+
+func (p *lfuPolicy) remove(key string) {
+	node, ok := p.nodes[key]
+	if !ok {
+		return
+	}
+
+	heap.Remove(&p.heap, node.index)
+	delete(p.nodes, key)
+}
+
+func (p *lfuPolicy) evict() (string, bool) {
+	if len(p.heap) == 0 {
+		return "", false
+	}
+
+	victim := heap.Pop(&p.heap).(*lfuNode)
+	delete(p.nodes, victim.key)
+	return victim.key, true
+}
+
+type lfuHeap []*lfuNode
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x any) {
+	node := x.(*lfuNode)
+	node.index = len(*h)
+	*h = append(*h, node)
+}
+
+func (h *lfuHeap) Pop() any {
+	old := *h
+	node := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return node
+}
@@ -2,30 +2,31 @@ package cache
 
 import (
 	"container/list"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/torrentxok/order_service/internal/models"
 )
 
-type entry struct {
-	key   string
-	value *models.Order
-}
-
 type LRUCache struct {
 	capacity int
+	opts     options
 	items    map[string]*list.Element
 	list     *list.List
 	mu       sync.Mutex
+
+	counters
 }
 
-func NewLRUCache(capacity int) *LRUCache {
+func NewLRUCache(capacity int, opts ...Option) *LRUCache {
 	if capacity <= 0 {
 		panic("cache capacity must be positive")
 	}
 
 	return &LRUCache{
 		capacity: capacity,
+		opts:     newOptions(opts),
 		items:    make(map[string]*list.Element),
 		list:     list.New(),
 	}
@@ -35,28 +36,61 @@ func (c *LRUCache) Get(key string) (*models.Order, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if elem, ok := c.items[key]; ok {
-		c.list.MoveToFront(elem)
-		return elem.Value.(*entry).value, true
+	elem, ok := c.items[key]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
 	}
 
-	return nil, false
+	ent := elem.Value.(*entry)
+	if ent.expired(c.opts.clock.Now()) {
+		c.remove(elem)
+		c.expirations.Add(1)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.list.MoveToFront(elem)
+	c.hits.Add(1)
+	return ent.value, true
 }
 
 func (c *LRUCache) Set(key string, value *models.Order) {
+	c.SetWithTTL(key, value, c.opts.ttl)
+}
+
+func (c *LRUCache) SetWithTTL(key string, value *models.Order, ttl time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if elem, ok := c.items[key]; ok {
-		elem.Value.(*entry).value = value
-		c.list.MoveToFront(elem)
+	storedAt := c.opts.clock.Now()
+	expiresAt := c.opts.expiresAt(ttl)
+
+	size := entrySize(key, value)
+
+	if c.opts.tooLarge(size) {
+		// старое значение устарело, а новое не кэшируется
+		if elem, ok := c.items[key]; ok {
+			c.remove(elem)
+		}
 		return
 	}
 
-	elem := c.list.PushFront(&entry{key: key, value: value})
-	c.items[key] = elem
+	if elem, ok := c.items[key]; ok {
+		ent := elem.Value.(*entry)
+		c.bytes.Add(size - ent.size)
+		ent.value = value
+		ent.size = size
+		ent.expiresAt = expiresAt
+		ent.storedAt = storedAt
+		c.list.MoveToFront(elem)
+	} else {
+		c.items[key] = c.list.PushFront(&entry{key: key, value: value, size: size, expiresAt: expiresAt, storedAt: storedAt})
+		c.length.Add(1)
+		c.bytes.Add(size)
+	}
 
-	if c.list.Len() > c.capacity {
+	for c.list.Len() > 0 && c.opts.overBudget(c.list.Len(), c.capacity, c.bytes.Load()) {
 		c.evict()
 	}
 }
@@ -66,23 +100,104 @@ func (c *LRUCache) Delete(key string) {
 	defer c.mu.Unlock()
 
 	if elem, ok := c.items[key]; ok {
-		c.list.Remove(elem)
-		ent := elem.Value.(*entry)
-		delete(c.items, ent.key)
+		c.remove(elem)
 	}
 }
 
 func (c *LRUCache) Capacity() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	return c.capacity
 }
 
+// Resize меняет ёмкость на ходу; при уменьшении лишние записи вытесняются сразу
+func (c *LRUCache) Resize(capacity int) error {
+	if capacity <= 0 {
+		return fmt.Errorf("cache capacity must be positive, got %d", capacity)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capacity = capacity
+	for c.list.Len() > 0 && c.opts.overBudget(c.list.Len(), c.capacity, c.bytes.Load()) {
+		c.evict()
+	}
+	return nil
+}
+
+func (c *LRUCache) Peek(key string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+
+	ent := elem.Value.(*entry)
+	if ent.expired(c.opts.clock.Now()) {
+		return Entry{}, false
+	}
+	return ent.export(), true
+}
+
+func (c *LRUCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.list.Init()
+	c.length.Store(0)
+	c.bytes.Store(0)
+}
+
+func (c *LRUCache) DeleteExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.opts.clock.Now()
+	removed := 0
+	for elem := c.list.Back(); elem != nil; {
+		prev := elem.Prev()
+		if elem.Value.(*entry).expired(now) {
+			c.remove(elem)
+			removed++
+		}
+		elem = prev
+	}
+	c.expirations.Add(uint64(removed))
+
+	return removed
+}
+
+func (c *LRUCache) Entries() []Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]Entry, 0, c.list.Len())
+	for elem := c.list.Front(); elem != nil; elem = elem.Next() {
+		entries = append(entries, elem.Value.(*entry).export())
+	}
+	return entries
+}
+
+func (c *LRUCache) Stats() Stats {
+	return c.stats(c.Capacity(), c.opts.maxBytes)
+}
+
 func (c *LRUCache) evict() {
-	elem := c.list.Back()
-	if elem == nil {
-		return
+	if elem := c.list.Back(); elem != nil {
+		c.remove(elem)
+		c.evictions.Add(1)
 	}
+}
 
-	c.list.Remove(elem)
+func (c *LRUCache) remove(elem *list.Element) {
 	ent := elem.Value.(*entry)
+	c.list.Remove(elem)
 	delete(c.items, ent.key)
+	c.length.Add(-1)
+	c.bytes.Add(-ent.size)
 }
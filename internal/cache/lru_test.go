@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUDefaultTTL(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLRUCache(10, WithTTL(time.Minute), WithClock(clock))
+
+	c.Set("a", testOrder("a"))
+
+	clock.Advance(59 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("entry expired before TTL")
+	}
+
+	clock.Advance(time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("entry still returned after TTL")
+	}
+}
+
+func TestLRUSetWithTTLOverridesDefault(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLRUCache(10, WithTTL(time.Minute), WithClock(clock))
+
+	c.SetWithTTL("short", testOrder("short"), 10*time.Second)
+	c.SetWithTTL("long", testOrder("long"), time.Hour)
+	// 0 - без срока, независимо от TTL по умолчанию
+	c.SetWithTTL("forever", testOrder("forever"), 0)
+
+	clock.Advance(10 * time.Second)
+	if _, ok := c.Get("short"); ok {
+		t.Fatal("short entry outlived its own TTL")
+	}
+
+	clock.Advance(time.Minute)
+	if _, ok := c.Get("long"); !ok {
+		t.Fatal("long entry expired at the default TTL")
+	}
+
+	clock.Advance(24 * time.Hour)
+	if _, ok := c.Get("forever"); !ok {
+		t.Fatal("entry without TTL expired")
+	}
+
+	e, _ := c.Peek("forever")
+	if !e.ExpiresAt.IsZero() {
+		t.Fatalf("ExpiresAt = %v, want zero", e.ExpiresAt)
+	}
+}
+
+func TestLRULazyExpiryOnGet(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLRUCache(10, WithTTL(time.Minute), WithClock(clock))
+
+	c.Set("a", testOrder("a"))
+	c.Set("b", testOrder("b"))
+	clock.Advance(time.Minute)
+
+	// без janitor запись лежит до первого чтения
+	if got := c.Stats().Len; got != 2 {
+		t.Fatalf("Len before Get = %d, want 2", got)
+	}
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expired entry returned")
+	}
+
+	st := c.Stats()
+	if st.Len != 1 {
+		t.Fatalf("Len after Get = %d, want 1", st.Len)
+	}
+	if st.Expirations != 1 || st.Misses != 1 || st.Hits != 0 {
+		t.Fatalf("stats = %+v, want 1 expiration and 1 miss", st)
+	}
+
+	// Peek не удаляет, но и не отдаёт истёкшую запись
+	if _, ok := c.Peek("b"); ok {
+		t.Fatal("Peek returned expired entry")
+	}
+	if got := c.Stats().Len; got != 1 {
+		t.Fatalf("Len after Peek = %d, want 1", got)
+	}
+}
+
+func TestLRUDeleteExpired(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLRUCache(10, WithClock(clock))
+
+	c.SetWithTTL("a", testOrder("a"), time.Minute)
+	c.SetWithTTL("b", testOrder("b"), 2*time.Minute)
+	c.SetWithTTL("c", testOrder("c"), 0)
+
+	if n := c.DeleteExpired(); n != 0 {
+		t.Fatalf("DeleteExpired = %d before expiry, want 0", n)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if n := c.DeleteExpired(); n != 2 {
+		t.Fatalf("DeleteExpired = %d, want 2", n)
+	}
+
+	st := c.Stats()
+	if st.Len != 1 || st.Expirations != 2 {
+		t.Fatalf("stats = %+v, want Len 1 and 2 expirations", st)
+	}
+	if st.Bytes != entrySize("c", testOrder("c")) {
+		t.Fatalf("Bytes = %d, want size of the remaining entry", st.Bytes)
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Fatal("entry without TTL was removed")
+	}
+}
@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// negativeStripes - на столько групп делятся ключи для поколений: Remove сбрасывает
+// загрузки только своей группы, а не все идущие параллельно
+const negativeStripes = 256
+
+// NegativeCache помнит ключи, которых нет в БД: ограничен по числу ключей (LRU)
+// и по времени. Remove сбрасывает и загрузки, начатые до него, см. Generation.
+type NegativeCache struct {
+	capacity int
+	ttl      time.Duration
+	clock    Clock
+	items    map[string]*list.Element
+	list     *list.List
+	gens     [negativeStripes]uint64
+	mu       sync.Mutex
+}
+
+type negativeEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+func NewNegativeCache(capacity int, ttl time.Duration, opts ...Option) *NegativeCache {
+	if capacity <= 0 {
+		panic("negative cache capacity must be positive")
+	}
+
+	return &NegativeCache{
+		capacity: capacity,
+		ttl:      ttl,
+		clock:    newOptions(opts).clock,
+		items:    make(map[string]*list.Element),
+		list:     list.New(),
+	}
+}
+
+func stripe(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % negativeStripes)
+}
+
+// Generation берётся до запроса в БД и передаётся в Add
+func (c *NegativeCache) Generation(key string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.gens[stripe(key)]
+}
+
+// Add запоминает отсутствие ключа, если с момента gen не было Remove этого ключа
+// (или соседнего по группе): иначе заказ мог появиться, пока шёл запрос
+func (c *NegativeCache) Add(key string, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gens[stripe(key)] {
+		return
+	}
+
+	expiresAt := c.clock.Now().Add(c.ttl)
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*negativeEntry).expiresAt = expiresAt
+		c.list.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.list.PushFront(&negativeEntry{key: key, expiresAt: expiresAt})
+
+	if c.list.Len() > c.capacity {
+		c.remove(c.list.Back())
+	}
+}
+
+func (c *NegativeCache) Contains(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return false
+	}
+
+	if !c.clock.Now().Before(elem.Value.(*negativeEntry).expiresAt) {
+		c.remove(elem)
+		return false
+	}
+
+	return true
+}
+
+func (c *NegativeCache) Remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gens[stripe(key)]++
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+}
+
+func (c *NegativeCache) remove(elem *list.Element) {
+	c.list.Remove(elem)
+	delete(c.items, elem.Value.(*negativeEntry).key)
+}
+
+// Purge забывает все ключи и, как Remove, сбрасывает начатые загрузки
+func (c *NegativeCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.gens {
+		c.gens[i]++
+	}
+	c.items = make(map[string]*list.Element)
+	c.list.Init()
+}
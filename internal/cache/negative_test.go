@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNegativeRemoveDropsOnlyOwnLoads(t *testing.T) {
+	c := NewNegativeCache(10, time.Minute)
+
+	// ключ из другой группы поколений
+	other := "b"
+	for i := 0; stripe(other) == stripe("a"); i++ {
+		other = "b-" + strconv.Itoa(i)
+	}
+
+	genA, genOther := c.Generation("a"), c.Generation(other)
+
+	// пока шли обе загрузки, заказ other создали
+	c.Remove(other)
+
+	c.Add("a", genA)
+	c.Add(other, genOther)
+
+	if !c.Contains("a") {
+		t.Fatal("Remove of another key discarded the load of a")
+	}
+	if c.Contains(other) {
+		t.Fatal("load started before Remove was cached")
+	}
+}
+
+func TestNegativeTTL(t *testing.T) {
+	clock := newFakeClock()
+	c := NewNegativeCache(10, time.Minute, WithClock(clock))
+
+	c.Add("a", c.Generation("a"))
+	if !c.Contains("a") {
+		t.Fatal("key was not remembered")
+	}
+
+	clock.Advance(time.Minute)
+	if c.Contains("a") {
+		t.Fatal("key outlived its TTL")
+	}
+}
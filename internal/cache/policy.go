@@ -0,0 +1,224 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/models"
+)
+
+const (
+	PolicyLRU     = "lru"
+	PolicyLFU     = "lfu"
+	PolicyARC     = "arc"
+	PolicyTinyLFU = "tinylfu"
+)
+
+// New создаёт кэш с заданной политикой вытеснения
+func New(policy string, capacity int, opts ...Option) (OrderCache, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("cache capacity must be positive, got %d", capacity)
+	}
+
+	switch policy {
+	case PolicyLRU, "":
+		return NewLRUCache(capacity, opts...), nil
+	case PolicyLFU:
+		return newPolicyCache(capacity, func(int) evictionPolicy { return newLFUPolicy() }, opts), nil
+	case PolicyARC:
+		return newPolicyCache(capacity, func(n int) evictionPolicy { return newARCPolicy(n) }, opts), nil
+	case PolicyTinyLFU:
+		return newPolicyCache(capacity, func(n int) evictionPolicy { return newTinyLFUPolicy(n) }, opts), nil
+	default:
+		return nil, fmt.Errorf("unknown cache policy %q", policy)
+	}
+}
+
+// evictionPolicy хранит только ключи и решает, кого вытеснять.
+// Все методы вызываются под мьютексом policyCache.
+type evictionPolicy interface {
+	// add регистрирует новый ключ и возвращает ключи, которые нужно выбросить
+	add(key string, capacity int) []string
+	// touch - попадание по ключу
+	touch(key string)
+	remove(key string)
+	// evict выбирает и убирает одну жертву, когда не хватает места по объёму
+	evict() (string, bool)
+}
+
+// policyCache - общая часть кэшей с нестандартным вытеснением: значения, TTL, счётчики
+type policyCache struct {
+	capacity  int
+	opts      options
+	newPolicy func(capacity int) evictionPolicy
+	policy    evictionPolicy
+	items     map[string]*entry
+	mu        sync.Mutex
+
+	counters
+}
+
+func newPolicyCache(capacity int, newPolicy func(int) evictionPolicy, opts []Option) *policyCache {
+	return &policyCache{
+		capacity:  capacity,
+		opts:      newOptions(opts),
+		newPolicy: newPolicy,
+		policy:    newPolicy(capacity),
+		items:     make(map[string]*entry),
+	}
+}
+
+func (c *policyCache) Get(key string) (*models.Order, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ent, ok := c.items[key]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	if ent.expired(c.opts.clock.Now()) {
+		c.remove(key)
+		c.expirations.Add(1)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.policy.touch(key)
+	c.hits.Add(1)
+	return ent.value, true
+}
+
+func (c *policyCache) Set(key string, value *models.Order) {
+	c.SetWithTTL(key, value, c.opts.ttl)
+}
+
+func (c *policyCache) SetWithTTL(key string, value *models.Order, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	storedAt := c.opts.clock.Now()
+	expiresAt := c.opts.expiresAt(ttl)
+
+	size := entrySize(key, value)
+
+	if c.opts.tooLarge(size) {
+		// старое значение устарело, а новое не кэшируется
+		if _, ok := c.items[key]; ok {
+			c.remove(key)
+		}
+		return
+	}
+
+	if ent, ok := c.items[key]; ok {
+		c.bytes.Add(size - ent.size)
+		ent.value = value
+		ent.size = size
+		ent.expiresAt = expiresAt
+		ent.storedAt = storedAt
+		c.policy.touch(key)
+	} else {
+		c.items[key] = &entry{key: key, value: value, size: size, expiresAt: expiresAt, storedAt: storedAt}
+		c.length.Add(1)
+		c.bytes.Add(size)
+
+		for _, evicted := range c.policy.add(key, c.capacity) {
+			c.drop(evicted)
+		}
+	}
+
+	for len(c.items) > 0 && c.opts.overBudget(len(c.items), c.capacity, c.bytes.Load()) {
+		victim, ok := c.policy.evict()
+		if !ok {
+			break
+		}
+		c.drop(victim)
+	}
+}
+
+// drop - запись, вытесненная политикой; из самой политики она уже убрана
+func (c *policyCache) drop(key string) {
+	if ent, ok := c.items[key]; ok {
+		delete(c.items, key)
+		c.length.Add(-1)
+		c.bytes.Add(-ent.size)
+		c.evictions.Add(1)
+	}
+}
+
+func (c *policyCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.items[key]; ok {
+		c.remove(key)
+	}
+}
+
+func (c *policyCache) Capacity() int {
+	return c.capacity
+}
+
+func (c *policyCache) DeleteExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.opts.clock.Now()
+	removed := 0
+	for key, ent := range c.items {
+		if ent.expired(now) {
+			c.remove(key)
+			removed++
+		}
+	}
+	c.expirations.Add(uint64(removed))
+
+	return removed
+}
+
+func (c *policyCache) Entries() []Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]Entry, 0, len(c.items))
+	for _, ent := range c.items {
+		entries = append(entries, ent.export())
+	}
+	return entries
+}
+
+func (c *policyCache) Peek(key string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ent, ok := c.items[key]
+	if !ok || ent.expired(c.opts.clock.Now()) {
+		return Entry{}, false
+	}
+	return ent.export(), true
+}
+
+// Purge сбрасывает и историю политики: частоты и призраки относятся к удалённым записям
+func (c *policyCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]*entry)
+	c.policy = c.newPolicy(c.capacity)
+	c.length.Store(0)
+	c.bytes.Store(0)
+}
+
+func (c *policyCache) Stats() Stats {
+	return c.stats(c.capacity, c.opts.maxBytes)
+}
+
+func (c *policyCache) remove(key string) {
+	ent := c.items[key]
+	delete(c.items, key)
+	c.policy.remove(key)
+	c.length.Add(-1)
+	c.bytes.Add(-ent.size)
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func mustNew(t *testing.T, policy string, capacity int, opts ...Option) OrderCache {
+	t.Helper()
+
+	c, err := New(policy, capacity, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestLFUEvictsLeastFrequent(t *testing.T) {
+	c := mustNew(t, PolicyLFU, 3)
+
+	for _, k := range []string{"a", "b", "c"} {
+		c.Set(k, testOrder(k))
+	}
+	c.Get("a")
+	c.Get("a")
+	c.Get("c")
+
+	c.Set("d", testOrder("d"))
+
+	if _, ok := c.Peek("b"); ok {
+		t.Fatal("least frequently used key b survived")
+	}
+	for _, k := range []string{"a", "c", "d"} {
+		if _, ok := c.Peek(k); !ok {
+			t.Fatalf("key %s was evicted instead of b", k)
+		}
+	}
+}
+
+func TestARCKeepsHotKeysThroughScan(t *testing.T) {
+	c := mustNew(t, PolicyARC, 10)
+
+	hot := []string{"h0", "h1", "h2", "h3", "h4"}
+	for _, k := range hot {
+		c.Set(k, testOrder(k))
+		c.Get(k)
+	}
+
+	// длинный скан однократно прочитанных заказов
+	for i := range 100 {
+		k := "scan-" + strconv.Itoa(i)
+		c.Set(k, testOrder(k))
+	}
+
+	for _, k := range hot {
+		if _, ok := c.Peek(k); !ok {
+			t.Fatalf("hot key %s was evicted by the scan", k)
+		}
+	}
+}
+
+func TestARCTrimsGhostsUnderByteBudget(t *testing.T) {
+	const capacity = 10
+
+	small := entrySize("k-000", testOrder("k-000"))
+	big := testOrder("big")
+	big.Delivery.Address = strings.Repeat("x", int(4*small))
+
+	// по объёму влезают шесть маленьких записей или одна большая
+	c := mustNew(t, PolicyARC, capacity, WithMaxBytes(6*small))
+	p := c.(*policyCache).policy.(*arcPolicy)
+
+	check := func() {
+		t.Helper()
+		if n := p.t1.Len() + p.b1.Len(); n > capacity {
+			t.Fatalf("|t1|+|b1| = %d, want <= %d", n, capacity)
+		}
+		if n := p.t1.Len() + p.t2.Len() + p.b1.Len() + p.b2.Len(); n > 2*capacity {
+			t.Fatalf("ARC tracks %d keys, want <= %d", n, 2*capacity)
+		}
+		if len(p.nodes) != p.t1.Len()+p.t2.Len()+p.b1.Len()+p.b2.Len() {
+			t.Fatalf("nodes = %d, out of sync with the lists", len(p.nodes))
+		}
+	}
+
+	// вперемешку чтения, маленькие и большие записи: большая вытесняет несколько ключей за раз
+	r := rand.New(rand.NewSource(1))
+	for range 5000 {
+		k := "k-" + strconv.Itoa(r.Intn(100))
+		switch r.Intn(3) {
+		case 0:
+			c.Get(k)
+		case 1:
+			c.Set(k, testOrder(k))
+		case 2:
+			c.Set(k, big)
+		}
+		check()
+	}
+}
+
+func TestTinyLFURejectsOneHitKey(t *testing.T) {
+	// окно на 1 ключ, основная область на 9
+	c := mustNew(t, PolicyTinyLFU, 10)
+
+	hot := make([]string, 9)
+	for i := range hot {
+		hot[i] = "hot-" + strconv.Itoa(i)
+		c.Set(hot[i], testOrder(hot[i]))
+	}
+	for range 3 {
+		for _, k := range hot {
+			c.Get(k)
+		}
+	}
+
+	// "once" выходит из окна при следующей вставке и проигрывает жертве основной области
+	c.Set("once", testOrder("once"))
+	c.Set("next", testOrder("next"))
+
+	if _, ok := c.Peek("once"); ok {
+		t.Fatal("one-hit key was admitted over a hot one")
+	}
+	for _, k := range hot {
+		if _, ok := c.Peek(k); !ok {
+			t.Fatalf("hot key %s was evicted", k)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/pii"
+)
+
+const (
+	// после стольких ошибок подряд Redis не опрашивается breakerCooldown
+	breakerThreshold = 5
+	breakerCooldown  = 5 * time.Second
+)
+
+// RedisCache - общий для всех реплик кэш второго уровня.
+// Любая ошибка Redis считается промахом: запрос уйдёт в БД, а не упадёт.
+// Когда Redis лежит, каждое обращение ждало бы таймаут, поэтому после
+// breakerThreshold ошибок подряд чтение и запись пропускаются на breakerCooldown.
+// С cipher заказ хранится зашифрованным: в нём ПДн получателя.
+type RedisCache struct {
+	client  redis.UniversalClient
+	cipher  *pii.Cipher
+	prefix  string
+	ttl     time.Duration
+	timeout time.Duration
+	clock   Clock
+
+	// ошибки подряд; не сбрасываются при размыкании, чтобы первая же
+	// неудачная попытка после паузы снова разомкнула цепь
+	failures atomic.Int32
+	// unix nano, до которого Redis не опрашивается
+	openUntil atomic.Int64
+
+	counters
+	errors atomic.Uint64
+}
+
+// NewRedisCache: cipher nil - значения пишутся открытым JSON
+func NewRedisCache(client redis.UniversalClient, cipher *pii.Cipher, prefix string, ttl, timeout time.Duration) *RedisCache {
+	return &RedisCache{
+		client:  client,
+		cipher:  cipher,
+		prefix:  prefix,
+		ttl:     ttl,
+		timeout: timeout,
+		clock:   realClock{},
+	}
+}
+
+func (c *RedisCache) ctx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.timeout)
+}
+
+// available - false, пока цепь разомкнута
+func (c *RedisCache) available() bool {
+	return c.clock.Now().UnixNano() >= c.openUntil.Load()
+}
+
+// fail учитывает ошибку Redis (не redis.Nil) и размыкает цепь после порога
+func (c *RedisCache) fail() {
+	c.errors.Add(1)
+	if c.failures.Add(1) >= breakerThreshold {
+		c.openUntil.Store(c.clock.Now().Add(breakerCooldown).UnixNano())
+	}
+}
+
+func (c *RedisCache) succeed() {
+	c.failures.Store(0)
+}
+
+func (c *RedisCache) encode(order *models.Order) ([]byte, error) {
+	data, err := json.Marshal(order)
+	if err != nil || c.cipher == nil {
+		return data, err
+	}
+
+	sealed, err := c.cipher.Seal(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sealedEnvelope{KeyID: sealed.KeyID, WrappedDEK: sealed.WrappedDEK, Data: sealed.Fields[0]})
+}
+
+// decode не различает повреждённое и записанное с другими ключами значение: оба - промах
+func (c *RedisCache) decode(data []byte) (*models.Order, error) {
+	if c.cipher != nil {
+		var env sealedEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			return nil, err
+		}
+
+		plain, err := c.cipher.Open(env.KeyID, env.WrappedDEK, env.Data)
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(plain[0])
+	}
+
+	var order models.Order
+	if err := json.Unmarshal(data, &order); err != nil {
+		return nil, err
+	}
+	// конверт без ключей разбирается в пустой заказ
+	if order.OrderUID == "" {
+		return nil, errors.New("redis value is not an order")
+	}
+	return &order, nil
+}
+
+func (c *RedisCache) Get(key string) (*models.Order, bool) {
+	if !c.available() {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	ctx, cancel := c.ctx()
+	defer cancel()
+
+	data, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			c.succeed()
+		} else {
+			c.fail()
+		}
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.succeed()
+
+	order, err := c.decode(data)
+	if err != nil {
+		c.errors.Add(1)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.hits.Add(1)
+	return order, true
+}
+
+func (c *RedisCache) Set(key string, value *models.Order) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+func (c *RedisCache) SetWithTTL(key string, value *models.Order, ttl time.Duration) {
+	data, err := c.encode(value)
+	if err != nil {
+		c.errors.Add(1)
+		return
+	}
+
+	if !c.available() {
+		return
+	}
+
+	ctx, cancel := c.ctx()
+	defer cancel()
+
+	// ttl <= 0 - без срока, как и у локальных кэшей
+	if err := c.client.Set(ctx, c.prefix+key, data, max(ttl, 0)).Err(); err != nil {
+		c.fail()
+		return
+	}
+	c.succeed()
+}
+
+// Delete не пропускается даже при разомкнутой цепи: инвалидация важнее задержки,
+// иначе после восстановления Redis отдавал бы устаревший заказ до истечения TTL
+func (c *RedisCache) Delete(key string) {
+	ctx, cancel := c.ctx()
+	defer cancel()
+
+	if err := c.client.Del(ctx, c.prefix+key).Err(); err != nil {
+		c.fail()
+		return
+	}
+	c.succeed()
+}
+
+// Capacity - у Redis своя политика памяти (maxmemory)
+func (c *RedisCache) Capacity() int {
+	return 0
+}
+
+func (c *RedisCache) Stats() Stats {
+	st := c.stats(0, 0)
+	st.Errors = c.errors.Load()
+	return st
+}
+
+// DeleteExpired - истечением занимается сам Redis
+func (c *RedisCache) DeleteExpired() int {
+	return 0
+}
+
+// Entries - содержимое Redis общее и может быть огромным, не перечисляется
+func (c *RedisCache) Entries() []Entry {
+	return nil
+}
+
+// Peek не знает, когда запись положили: StoredAt нулевой
+func (c *RedisCache) Peek(key string) (Entry, bool) {
+	if !c.available() {
+		return Entry{}, false
+	}
+
+	ctx, cancel := c.ctx()
+	defer cancel()
+
+	pipe := c.client.Pipeline()
+	get := pipe.Get(ctx, c.prefix+key)
+	ttl := pipe.PTTL(ctx, c.prefix+key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		if errors.Is(err, redis.Nil) {
+			c.succeed()
+		} else {
+			c.fail()
+		}
+		return Entry{}, false
+	}
+	c.succeed()
+
+	order, err := c.decode([]byte(get.Val()))
+	if err != nil {
+		c.errors.Add(1)
+		return Entry{}, false
+	}
+
+	e := Entry{Key: key, Value: order}
+	if d := ttl.Val(); d > 0 {
+		e.ExpiresAt = c.clock.Now().Add(d)
+	}
+	return e, true
+}
+
+// Purge удаляет все ключи с префиксом сервиса пачками через SCAN
+func (c *RedisCache) Purge() {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*c.timeout)
+	defer cancel()
+
+	iter := c.client.Scan(ctx, 0, c.prefix+"*", 1000).Iterator()
+	var batch []string
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == 1000 {
+			if err := c.client.Unlink(ctx, batch...).Err(); err != nil {
+				c.errors.Add(1)
+				return
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		c.errors.Add(1)
+		return
+	}
+	if len(batch) > 0 {
+		if err := c.client.Unlink(ctx, batch...).Err(); err != nil {
+			c.errors.Add(1)
+		}
+	}
+}
+
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
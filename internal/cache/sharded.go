@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/models"
+)
+
+// ShardedCache - N независимых кэшей, шард выбирается хэшем ключа.
+// Чтения разных ключей не ждут друг друга на одном мьютексе;
+// порядок вытеснения соблюдается только внутри шарда.
+type ShardedCache struct {
+	shards   []OrderCache
+	capacity atomic.Int64
+}
+
+// NewShardedCache делит capacity поровну между shards, округляя вверх
+func NewShardedCache(policy string, capacity, shards int, opts ...Option) (*ShardedCache, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("cache capacity must be positive, got %d", capacity)
+	}
+	if shards <= 0 {
+		return nil, fmt.Errorf("cache shards must be positive, got %d", shards)
+	}
+
+	perShard := (capacity + shards - 1) / shards
+
+	// бюджет по объёму тоже делится между шардами
+	if o := newOptions(opts); o.maxBytes > 0 {
+		opts = append(opts[:len(opts):len(opts)], WithMaxBytes(max(1, o.maxBytes/int64(shards))))
+	}
+
+	c := &ShardedCache{shards: make([]OrderCache, shards)}
+	c.capacity.Store(int64(perShard * shards))
+	for i := range c.shards {
+		shard, err := New(policy, perShard, opts...)
+		if err != nil {
+			return nil, err
+		}
+		c.shards[i] = shard
+	}
+
+	return c, nil
+}
+
+func (c *ShardedCache) shard(key string) OrderCache {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return c.shards[h.Sum32()%uint32(len(c.shards))]
+}
+
+func (c *ShardedCache) Get(key string) (*models.Order, bool) {
+	return c.shard(key).Get(key)
+}
+
+func (c *ShardedCache) Set(key string, value *models.Order) {
+	c.shard(key).Set(key, value)
+}
+
+func (c *ShardedCache) SetWithTTL(key string, value *models.Order, ttl time.Duration) {
+	c.shard(key).SetWithTTL(key, value, ttl)
+}
+
+func (c *ShardedCache) Delete(key string) {
+	c.shard(key).Delete(key)
+}
+
+func (c *ShardedCache) Capacity() int {
+	return int(c.capacity.Load())
+}
+
+// Resize делит новую ёмкость между шардами так же, как конструктор
+func (c *ShardedCache) Resize(capacity int) error {
+	if capacity <= 0 {
+		return fmt.Errorf("cache capacity must be positive, got %d", capacity)
+	}
+
+	perShard := (capacity + len(c.shards) - 1) / len(c.shards)
+	for _, s := range c.shards {
+		r, ok := s.(Resizer)
+		if !ok {
+			return ErrResizeUnsupported
+		}
+		if err := r.Resize(perShard); err != nil {
+			return err
+		}
+	}
+
+	c.capacity.Store(int64(perShard * len(c.shards)))
+	return nil
+}
+
+func (c *ShardedCache) Peek(key string) (Entry, bool) {
+	return c.shard(key).Peek(key)
+}
+
+func (c *ShardedCache) Purge() {
+	for _, s := range c.shards {
+		s.Purge()
+	}
+}
+
+func (c *ShardedCache) Stats() Stats {
+	var total Stats
+	for _, s := range c.shards {
+		st := s.Stats()
+		total.Hits += st.Hits
+		total.Misses += st.Misses
+		total.Evictions += st.Evictions
+		total.Expirations += st.Expirations
+		total.Len += st.Len
+		total.Capacity += st.Capacity
+		total.Bytes += st.Bytes
+		total.MaxBytes += st.MaxBytes
+	}
+	return total
+}
+
+func (c *ShardedCache) DeleteExpired() int {
+	removed := 0
+	for _, s := range c.shards {
+		removed += s.DeleteExpired()
+	}
+	return removed
+}
+
+// Entries склеивает шарды: порядок соблюдается только внутри каждого
+func (c *ShardedCache) Entries() []Entry {
+	var entries []Entry
+	for _, s := range c.shards {
+		entries = append(entries, s.Entries()...)
+	}
+	return entries
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+const benchKeys = 10000
+
+// BenchmarkGet сравнивает конкуренцию за блокировку: один LRU против шардов.
+// Читателей ровно readers, b.N делится между ними.
+func BenchmarkGet(b *testing.B) {
+	caches := []struct {
+		name string
+		new  func() OrderCache
+	}{
+		{"lru", func() OrderCache { return NewLRUCache(benchKeys) }},
+		{"sharded16", func() OrderCache {
+			c, err := NewShardedCache(PolicyLRU, benchKeys, 16)
+			if err != nil {
+				b.Fatal(err)
+			}
+			return c
+		}},
+	}
+
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = "order-" + strconv.Itoa(i)
+	}
+
+	for _, tc := range caches {
+		c := tc.new()
+		for _, k := range keys {
+			c.Set(k, testOrder(k))
+		}
+
+		for _, readers := range []int{1, 8, 64} {
+			b.Run(fmt.Sprintf("%s/readers=%d", tc.name, readers), func(b *testing.B) {
+				var wg sync.WaitGroup
+
+				b.ResetTimer()
+				for r := range readers {
+					n := b.N / readers
+					if r < b.N%readers {
+						n++
+					}
+
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						// у каждого читателя своя последовательность ключей
+						i := r * 7919
+						for range n {
+							c.Get(keys[i%benchKeys])
+							i++
+						}
+					}()
+				}
+				wg.Wait()
+			})
+		}
+	}
+}
+
+func TestShardedCacheSpreadsKeys(t *testing.T) {
+	const shards, keys = 4, 256
+
+	// ёмкость с запасом: вытеснение не должно маскировать перекос
+	c, err := NewShardedCache(PolicyLRU, 4*keys, shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range keys {
+		k := "order-" + strconv.Itoa(i)
+		c.Set(k, testOrder(k))
+	}
+
+	if got := c.Stats().Len; got != keys {
+		t.Fatalf("Len = %d, want %d", got, keys)
+	}
+
+	mean := keys / shards
+	for i, shard := range c.shards {
+		if n := shard.Stats().Len; n == 0 || n > 2*mean {
+			t.Fatalf("shard %d holds %d keys, want 1..%d", i, n, 2*mean)
+		}
+	}
+}
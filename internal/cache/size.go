@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"unsafe"
+
+	"github.com/torrentxok/order_service/internal/models"
+)
+
+// entryOverhead - служебные структуры на запись: элемент списка, entry, слот map
+const entryOverhead = 160
+
+// EstimateSize - приблизительный объём заказа в памяти: структуры плюс содержимое строк
+func EstimateSize(o *models.Order) int64 {
+	if o == nil {
+		return 0
+	}
+
+	size := int(unsafe.Sizeof(*o)) +
+		len(o.OrderUID) + len(o.TrackNumber) + len(o.Entry) + len(o.Locale) +
+		len(o.InternalSig) + len(o.CustomerID) + len(o.DeliveryService) +
+		len(o.ShardKey) + len(o.DateCreated) + len(o.OofShard)
+
+	d := &o.Delivery
+	size += len(d.Name) + len(d.Phone) + len(d.Zip) + len(d.City) +
+		len(d.Address) + len(d.Region) + len(d.Email)
+
+	p := &o.Payment
+	size += len(p.Transaction) + len(p.RequestID) + len(p.Currency) + len(p.Provider) + len(p.Bank)
+
+	size += cap(o.Items) * int(unsafe.Sizeof(models.Item{}))
+	for i := range o.Items {
+		it := &o.Items[i]
+		size += len(it.TrackNumber) + len(it.Rid) + len(it.Name) + len(it.Size) + len(it.Brand)
+	}
+
+	return int64(size)
+}
+
+func entrySize(key string, value *models.Order) int64 {
+	return entryOverhead + int64(len(key)) + EstimateSize(value)
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var policies = []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU}
+
+func TestMaxBytesEvictsDownToBudget(t *testing.T) {
+	budget := 5 * entrySize("k-00", testOrder("k-00"))
+
+	for _, policy := range policies {
+		t.Run(policy, func(t *testing.T) {
+			c := mustNew(t, policy, 100, WithMaxBytes(budget))
+
+			for i := range 20 {
+				k := "k-" + strconv.Itoa(i+10)
+				c.Set(k, testOrder(k))
+
+				if st := c.Stats(); st.Bytes > budget {
+					t.Fatalf("after %d inserts Bytes = %d, want <= %d", i+1, st.Bytes, budget)
+				}
+			}
+
+			st := c.Stats()
+			if st.Len == 0 || st.Len > 5 {
+				t.Fatalf("Len = %d, want 1..5", st.Len)
+			}
+			if st.Evictions == 0 {
+				t.Fatal("nothing was evicted past the budget")
+			}
+		})
+	}
+}
+
+func TestMaxBytesRejectsOversizedEntry(t *testing.T) {
+	budget := 5 * entrySize("k-00", testOrder("k-00"))
+
+	huge := testOrder("huge")
+	huge.Delivery.Address = strings.Repeat("x", int(budget))
+
+	for _, policy := range policies {
+		t.Run(policy, func(t *testing.T) {
+			c := mustNew(t, policy, 100, WithMaxBytes(budget))
+
+			keys := []string{"k-10", "k-11", "k-12"}
+			for _, k := range keys {
+				c.Set(k, testOrder(k))
+			}
+			before := c.Stats()
+
+			c.Set("huge", huge)
+
+			if _, ok := c.Peek("huge"); ok {
+				t.Fatal("entry larger than the budget was cached")
+			}
+			for _, k := range keys {
+				if _, ok := c.Peek(k); !ok {
+					t.Fatalf("key %s was evicted for an entry that cannot fit", k)
+				}
+			}
+			if st := c.Stats(); st.Bytes != before.Bytes || st.Evictions != before.Evictions {
+				t.Fatalf("stats = %+v, want unchanged %+v", st, before)
+			}
+
+			// обновление до слишком большого значения не оставляет старое
+			c.Set("k-10", huge)
+			if _, ok := c.Peek("k-10"); ok {
+				t.Fatal("stale value kept after oversized update")
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/pii"
+)
+
+// Формат снимка: magic, версия, время создания, sha256 от payload, флаги, затем
+// payload - gzip с NDJSON записей в порядке Entries (от горячих к холодным).
+// С флагом snapshotEncrypted payload - конверт sealedEnvelope с тем же gzip
+// внутри: в заказах ПДн получателя, и на диске они не должны лежать открытыми.
+const (
+	snapshotMagic      = "OCSN"
+	snapshotVersion    = 2
+	snapshotFlagsAt    = 4 + 2 + 8 + sha256.Size
+	snapshotHeaderSize = snapshotFlagsAt + 1
+
+	snapshotEncrypted = 1 << 0
+)
+
+var (
+	ErrSnapshotCorrupt = errors.New("cache snapshot is corrupt")
+	ErrSnapshotVersion = errors.New("unsupported cache snapshot version")
+	ErrSnapshotStale   = errors.New("cache snapshot is stale")
+	// снимок зашифрован, а ключи не настроены
+	ErrSnapshotEncrypted = errors.New("cache snapshot is encrypted")
+	// ключи настроены, а снимок открытый: его мог подложить кто угодно с доступом к файлу
+	ErrSnapshotUnencrypted = errors.New("cache snapshot is not encrypted")
+)
+
+// sealedEnvelope - зашифрованный payload: снимок или значение в Redis
+type sealedEnvelope struct {
+	KeyID      string `json:"key_id"`
+	WrappedDEK []byte `json:"wrapped_dek"`
+	Data       string `json:"data"`
+}
+
+// SaveSnapshot атомарно (через временный файл и rename) записывает содержимое кэша.
+// С cipher payload шифруется, nil - пишется открытым.
+func SaveSnapshot(path string, c OrderCache, cipher *pii.Cipher) (int, error) {
+	entries := c.Entries()
+
+	var payload bytes.Buffer
+	zw := gzip.NewWriter(&payload)
+	enc := json.NewEncoder(zw)
+	for i := range entries {
+		if err := enc.Encode(&entries[i]); err != nil {
+			return 0, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+
+	var flags byte
+	if cipher != nil {
+		sealed, err := cipher.Seal(payload.String())
+		if err != nil {
+			return 0, fmt.Errorf("encrypt snapshot: %w", err)
+		}
+
+		data, err := json.Marshal(sealedEnvelope{KeyID: sealed.KeyID, WrappedDEK: sealed.WrappedDEK, Data: sealed.Fields[0]})
+		if err != nil {
+			return 0, err
+		}
+
+		payload.Reset()
+		payload.Write(data)
+		flags |= snapshotEncrypted
+	}
+
+	header := make([]byte, snapshotHeaderSize)
+	copy(header, snapshotMagic)
+	binary.BigEndian.PutUint16(header[4:], snapshotVersion)
+	binary.BigEndian.PutUint64(header[6:], uint64(time.Now().UnixNano()))
+	sum := sha256.Sum256(payload.Bytes())
+	copy(header[14:], sum[:])
+	header[snapshotFlagsAt] = flags
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(header); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if _, err := payload.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// LoadSnapshot восстанавливает кэш из снимка не старше maxAge (0 - любой давности).
+// Истёкшие записи пропускаются, у остальных сохраняется оставшийся TTL.
+// Зашифрованный снимок читается только с cipher, а с cipher - только зашифрованный.
+func LoadSnapshot(path string, c OrderCache, maxAge time.Duration, cipher *pii.Cipher) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(data) < snapshotHeaderSize || string(data[:4]) != snapshotMagic {
+		return 0, ErrSnapshotCorrupt
+	}
+	if v := binary.BigEndian.Uint16(data[4:]); v != snapshotVersion {
+		return 0, fmt.Errorf("%w: %d", ErrSnapshotVersion, v)
+	}
+	flags := data[snapshotFlagsAt]
+
+	created := time.Unix(0, int64(binary.BigEndian.Uint64(data[6:])))
+	if maxAge > 0 && time.Since(created) > maxAge {
+		return 0, fmt.Errorf("%w: created %s", ErrSnapshotStale, created.Format(time.RFC3339))
+	}
+
+	payload := data[snapshotHeaderSize:]
+	if sum := sha256.Sum256(payload); !bytes.Equal(sum[:], data[14:snapshotFlagsAt]) {
+		return 0, fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupt)
+	}
+
+	if flags&snapshotEncrypted == 0 && cipher != nil {
+		return 0, ErrSnapshotUnencrypted
+	}
+	if flags&snapshotEncrypted != 0 {
+		if cipher == nil {
+			return 0, ErrSnapshotEncrypted
+		}
+
+		var env sealedEnvelope
+		if err := json.Unmarshal(payload, &env); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+		}
+
+		plain, err := cipher.Open(env.KeyID, env.WrappedDEK, env.Data)
+		if err != nil {
+			return 0, fmt.Errorf("decrypt snapshot: %w", err)
+		}
+		payload = []byte(plain[0])
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	defer zr.Close()
+
+	var entries []Entry
+	dec := json.NewDecoder(zr)
+	for {
+		var e Entry
+		if err := dec.Decode(&e); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return 0, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+		}
+		entries = append(entries, e)
+	}
+
+	// с холодного конца, чтобы самые горячие оказались последними использованными
+	now := time.Now()
+	restored := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Value == nil {
+			continue
+		}
+
+		if e.ExpiresAt.IsZero() {
+			c.Set(e.Key, e.Value)
+		} else if ttl := e.ExpiresAt.Sub(now); ttl > 0 {
+			c.SetWithTTL(e.Key, e.Value, ttl)
+		} else {
+			continue
+		}
+		restored++
+	}
+
+	return restored, nil
+}
@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/pii"
+)
+
+type testKeys struct{}
+
+func (testKeys) CurrentKeyID() string       { return "k1" }
+func (testKeys) Key(string) ([]byte, error) { return bytes.Repeat([]byte{1}, 32), nil }
+func (testKeys) KeyIDs() []string           { return []string{"k1"} }
+func (testKeys) IndexKey() []byte           { return bytes.Repeat([]byte{2}, 32) }
+
+func snapshotSource(t *testing.T) OrderCache {
+	t.Helper()
+
+	c := NewLRUCache(10)
+	o := testOrder("a")
+	o.Delivery = models.Delivery{Name: "Test Testov", Phone: "+9720000000"}
+	c.Set("a", o)
+	c.Set("b", testOrder("b"))
+	return c
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+
+	if _, err := SaveSnapshot(path, snapshotSource(t), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewLRUCache(10)
+	n, err := LoadSnapshot(path, dst, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("restored %d entries, want 2", n)
+	}
+	if o, ok := dst.Get("a"); !ok || o.Delivery.Name != "Test Testov" {
+		t.Fatalf("Get(a) = %+v, %v", o, ok)
+	}
+}
+
+func TestSnapshotEncrypted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	cipher := pii.NewCipher(testKeys{})
+
+	if _, err := SaveSnapshot(path, snapshotSource(t), cipher); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// открытый payload - gzip, но и его на диске быть не должно
+	if bytes.Contains(data[snapshotHeaderSize:], []byte{0x1f, 0x8b}) {
+		t.Fatal("encrypted snapshot contains a plain gzip stream")
+	}
+
+	if _, err := LoadSnapshot(path, NewLRUCache(10), 0, nil); !errors.Is(err, ErrSnapshotEncrypted) {
+		t.Fatalf("load without cipher: error = %v, want ErrSnapshotEncrypted", err)
+	}
+
+	dst := NewLRUCache(10)
+	n, err := LoadSnapshot(path, dst, 0, cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("restored %d entries, want 2", n)
+	}
+	if o, ok := dst.Get("a"); !ok || o.Delivery.Phone != "+9720000000" {
+		t.Fatalf("Get(a) = %+v, %v", o, ok)
+	}
+}
+
+func TestSnapshotChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+
+	if _, err := SaveSnapshot(path, snapshotSource(t), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadSnapshot(path, NewLRUCache(10), 0, nil); !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Fatalf("error = %v, want ErrSnapshotCorrupt", err)
+	}
+}
+
+func TestSnapshotPlainRejectedWithCipher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+
+	if _, err := SaveSnapshot(path, snapshotSource(t), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewLRUCache(10)
+	if _, err := LoadSnapshot(path, dst, 0, pii.NewCipher(testKeys{})); !errors.Is(err, ErrSnapshotUnencrypted) {
+		t.Fatalf("error = %v, want ErrSnapshotUnencrypted", err)
+	}
+	if got := dst.Stats().Len; got != 0 {
+		t.Fatalf("Len = %d after rejected load, want 0", got)
+	}
+}
+
+func TestSnapshotKeepsRecency(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+
+	src := NewLRUCache(3)
+	for _, k := range []string{"a", "b", "c"} {
+		src.Set(k, testOrder(k))
+	}
+	src.Get("a")
+
+	if _, err := SaveSnapshot(path, src, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewLRUCache(3)
+	if _, err := LoadSnapshot(path, dst, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := func(c OrderCache) []string {
+		var out []string
+		for _, e := range c.Entries() {
+			out = append(out, e.Key)
+		}
+		return out
+	}
+	if got, want := keys(dst), keys(src); !slices.Equal(got, want) {
+		t.Fatalf("restored order = %v, want %v", got, want)
+	}
+
+	// вытесняется самый холодный до сохранения ключ
+	dst.Set("d", testOrder("d"))
+	if _, ok := dst.Peek("b"); ok {
+		t.Fatal("coldest key b survived eviction")
+	}
+	if _, ok := dst.Peek("a"); !ok {
+		t.Fatal("hot key a was evicted")
+	}
+}
+
+func TestSnapshotMaxAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+
+	if _, err := SaveSnapshot(path, snapshotSource(t), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// время создания не входит в контрольную сумму, состариваем снимок на месте
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.BigEndian.PutUint64(data[6:], uint64(time.Now().Add(-2*time.Hour).UnixNano()))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewLRUCache(10)
+	if _, err := LoadSnapshot(path, dst, time.Hour, nil); !errors.Is(err, ErrSnapshotStale) {
+		t.Fatalf("error = %v, want ErrSnapshotStale", err)
+	}
+	if got := dst.Stats().Len; got != 0 {
+		t.Fatalf("Len = %d after stale snapshot, want 0", got)
+	}
+
+	if n, err := LoadSnapshot(path, dst, 3*time.Hour, nil); err != nil || n != 2 {
+		t.Fatalf("LoadSnapshot within maxAge = %d, %v; want 2, nil", n, err)
+	}
+}
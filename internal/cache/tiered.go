@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/torrentxok/order_service/internal/models"
+)
+
+// TieredCache - локальный l1 перед общим l2. Попадание в l2 поднимает запись в l1,
+// запись и удаление идут в оба уровня. Stats, Capacity и Entries - от l1.
+type TieredCache struct {
+	l1 OrderCache
+	l2 OrderCache
+}
+
+func NewTieredCache(l1, l2 OrderCache) *TieredCache {
+	return &TieredCache{l1: l1, l2: l2}
+}
+
+func (c *TieredCache) Get(key string) (*models.Order, bool) {
+	if order, ok := c.l1.Get(key); ok {
+		return order, true
+	}
+
+	order, ok := c.l2.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	c.l1.Set(key, order)
+	return order, true
+}
+
+func (c *TieredCache) Set(key string, value *models.Order) {
+	c.l1.Set(key, value)
+	c.l2.Set(key, value)
+}
+
+func (c *TieredCache) SetWithTTL(key string, value *models.Order, ttl time.Duration) {
+	c.l1.SetWithTTL(key, value, ttl)
+	c.l2.SetWithTTL(key, value, ttl)
+}
+
+func (c *TieredCache) Delete(key string) {
+	c.l1.Delete(key)
+	c.l2.Delete(key)
+}
+
+func (c *TieredCache) Capacity() int {
+	return c.l1.Capacity()
+}
+
+func (c *TieredCache) Resize(capacity int) error {
+	r, ok := c.l1.(Resizer)
+	if !ok {
+		return ErrResizeUnsupported
+	}
+	return r.Resize(capacity)
+}
+
+func (c *TieredCache) Peek(key string) (Entry, bool) {
+	if e, ok := c.l1.Peek(key); ok {
+		return e, true
+	}
+	return c.l2.Peek(key)
+}
+
+// Purge чистит и общий l2, иначе l1 тут же наполнится из него теми же данными
+func (c *TieredCache) Purge() {
+	c.l1.Purge()
+	c.l2.Purge()
+}
+
+func (c *TieredCache) Stats() Stats {
+	return c.l1.Stats()
+}
+
+func (c *TieredCache) DeleteExpired() int {
+	return c.l1.DeleteExpired()
+}
+
+func (c *TieredCache) Entries() []Entry {
+	return c.l1.Entries()
+}
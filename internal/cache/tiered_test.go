@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/pii"
+)
+
+func newTestRedisCache(t *testing.T, cipher *pii.Cipher) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{
+		Addr:          mr.Addr(),
+		MaxRetries:    -1,
+		DialTimeout:   100 * time.Millisecond,
+		DialerRetries: 1,
+	})
+
+	c := NewRedisCache(client, cipher, "order:", time.Minute, time.Second)
+	t.Cleanup(func() { c.Close() })
+	return c, mr
+}
+
+func TestTieredPromotesL2Hit(t *testing.T) {
+	l2, _ := newTestRedisCache(t, nil)
+	l1 := NewLRUCache(10)
+	c := NewTieredCache(l1, l2)
+
+	// запись от другой реплики: есть только в Redis
+	l2.Set("a", testOrder("a"))
+
+	order, ok := c.Get("a")
+	if !ok || order.OrderUID != "a" {
+		t.Fatalf("Get = %v, %v; want order a from l2", order, ok)
+	}
+	if _, ok := l1.Peek("a"); !ok {
+		t.Fatal("l2 hit was not promoted to l1")
+	}
+	if st := l2.Stats(); st.Hits != 1 {
+		t.Fatalf("l2 hits = %d, want 1", st.Hits)
+	}
+
+	// следующее чтение обслуживает l1
+	c.Get("a")
+	if st := l2.Stats(); st.Hits != 1 {
+		t.Fatalf("l2 hits after l1 hit = %d, want still 1", st.Hits)
+	}
+}
+
+func TestTieredWritesBothLevels(t *testing.T) {
+	l2, mr := newTestRedisCache(t, nil)
+	l1 := NewLRUCache(10)
+	c := NewTieredCache(l1, l2)
+
+	c.Set("a", testOrder("a"))
+	if !mr.Exists("order:a") {
+		t.Fatal("Set did not reach l2")
+	}
+
+	c.Delete("a")
+	if mr.Exists("order:a") {
+		t.Fatal("Delete did not reach l2")
+	}
+	if _, ok := l1.Peek("a"); ok {
+		t.Fatal("Delete did not reach l1")
+	}
+}
+
+func TestTieredDeadL2IsMiss(t *testing.T) {
+	l2, mr := newTestRedisCache(t, nil)
+	l1 := NewLRUCache(10)
+	c := NewTieredCache(l1, l2)
+
+	mr.Close()
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("Get with dead l2 returned a hit")
+	}
+
+	// запись в l1 работает и без Redis
+	c.Set("b", testOrder("b"))
+	if _, ok := c.Get("b"); !ok {
+		t.Fatal("l1 lost the entry while l2 is down")
+	}
+
+	if st := l2.Stats(); st.Errors == 0 {
+		t.Fatal("l2 errors were not counted")
+	}
+}
+
+func TestRedisBreaker(t *testing.T) {
+	l2, mr := newTestRedisCache(t, nil)
+	clock := newFakeClock()
+	l2.clock = clock
+
+	mr.Close()
+
+	for range breakerThreshold {
+		l2.Get("a")
+	}
+	if got := l2.Stats().Errors; got != breakerThreshold {
+		t.Fatalf("errors = %d, want %d", got, breakerThreshold)
+	}
+
+	// цепь разомкнута: Redis не опрашивается, обращения - быстрые промахи
+	l2.Get("a")
+	l2.Set("a", testOrder("a"))
+	if _, ok := l2.Peek("a"); ok {
+		t.Fatal("Peek returned a hit with open breaker")
+	}
+	if got := l2.Stats().Errors; got != breakerThreshold {
+		t.Fatalf("errors with open breaker = %d, want %d", got, breakerThreshold)
+	}
+
+	// после паузы одна неудачная попытка снова размыкает цепь
+	clock.Advance(breakerCooldown)
+	l2.Get("a")
+	l2.Get("a")
+	if got := l2.Stats().Errors; got != breakerThreshold+1 {
+		t.Fatalf("errors after cooldown = %d, want %d", got, breakerThreshold+1)
+	}
+
+	// Redis вернулся: после паузы обращения снова идут в него
+	if err := mr.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(breakerCooldown)
+
+	l2.Set("a", testOrder("a"))
+	if _, ok := l2.Get("a"); !ok {
+		t.Fatal("Get after recovery missed")
+	}
+	if got := l2.failures.Load(); got != 0 {
+		t.Fatalf("failures after recovery = %d, want 0", got)
+	}
+}
+
+func TestRedisEncryptsOrders(t *testing.T) {
+	cipher := pii.NewCipher(testKeys{})
+	c, mr := newTestRedisCache(t, cipher)
+
+	o := testOrder("a")
+	o.Delivery = models.Delivery{Name: "Test Testov", Phone: "+9720000000", Email: "test@gmail.com"}
+	c.Set("a", o)
+
+	stored, err := mr.Get("order:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plain := range []string{o.Delivery.Name, o.Delivery.Phone, o.Delivery.Email} {
+		if strings.Contains(stored, plain) {
+			t.Fatalf("redis value contains plaintext %q", plain)
+		}
+	}
+
+	got, ok := c.Get("a")
+	if !ok || got.Delivery.Phone != o.Delivery.Phone {
+		t.Fatalf("Get = %+v, %v; want decrypted order", got, ok)
+	}
+	if e, ok := c.Peek("a"); !ok || e.Value.Delivery.Email != o.Delivery.Email {
+		t.Fatalf("Peek = %+v, %v; want decrypted order", e.Value, ok)
+	}
+
+	// без ключей зашифрованное значение - промах, а не мусор
+	plain := NewRedisCache(c.client, nil, "order:", time.Minute, time.Second)
+	if _, ok := plain.Get("a"); ok {
+		t.Fatal("encrypted value was decoded without cipher")
+	}
+}
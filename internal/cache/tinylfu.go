@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"math/bits"
+)
+
+const (
+	segWindow = iota
+	segProbation
+	segProtected
+)
+
+// tinyLFUPolicy - W-TinyLFU: новые ключи попадают в маленькое LRU-окно (1%),
+// вытесненный из окна кандидат проходит в основную SLRU-область,
+// только если по частотному скетчу он популярнее её жертвы.
+// Однократно прочитанные при сканировании заказы не доходят до основной области.
+type tinyLFUPolicy struct {
+	windowCap    int
+	mainCap      int
+	protectedCap int
+
+	window, probation, protected *list.List
+	nodes                        map[string]*tinyLFUNode
+	sketch                       *countMinSketch
+}
+
+type tinyLFUNode struct {
+	segment int
+	elem    *list.Element
+}
+
+func newTinyLFUPolicy(capacity int) *tinyLFUPolicy {
+	windowCap := max(1, capacity/100)
+	mainCap := capacity - windowCap
+
+	return &tinyLFUPolicy{
+		windowCap:    windowCap,
+		mainCap:      mainCap,
+		protectedCap: mainCap * 8 / 10,
+		window:       list.New(),
+		probation:    list.New(),
+		protected:    list.New(),
+		nodes:        make(map[string]*tinyLFUNode),
+		sketch:       newCountMinSketch(capacity),
+	}
+}
+
+func (p *tinyLFUPolicy) add(key string, _ int) []string {
+	p.sketch.increment(key)
+
+	p.nodes[key] = &tinyLFUNode{segment: segWindow, elem: p.window.PushFront(key)}
+	if p.window.Len() <= p.windowCap {
+		return nil
+	}
+
+	candidate := p.window.Back().Value.(string)
+	p.window.Remove(p.window.Back())
+
+	if p.probation.Len()+p.protected.Len() < p.mainCap {
+		p.nodes[candidate] = &tinyLFUNode{segment: segProbation, elem: p.probation.PushFront(candidate)}
+		return nil
+	}
+
+	victimList := p.probation
+	if victimList.Len() == 0 {
+		victimList = p.protected
+	}
+	if victimList.Len() == 0 {
+		// основной области нет (ёмкость 1)
+		delete(p.nodes, candidate)
+		return []string{candidate}
+	}
+
+	victim := victimList.Back().Value.(string)
+	if p.sketch.estimate(candidate) <= p.sketch.estimate(victim) {
+		delete(p.nodes, candidate)
+		return []string{candidate}
+	}
+
+	victimList.Remove(victimList.Back())
+	delete(p.nodes, victim)
+	p.nodes[candidate] = &tinyLFUNode{segment: segProbation, elem: p.probation.PushFront(candidate)}
+	return []string{victim}
+}
+
+func (p *tinyLFUPolicy) touch(key string) {
+	p.sketch.increment(key)
+
+	node, ok := p.nodes[key]
+	if !ok {
+		return
+	}
+
+	switch node.segment {
+	case segWindow:
+		p.window.MoveToFront(node.elem)
+	case segProtected:
+		p.protected.MoveToFront(node.elem)
+	case segProbation:
+		p.probation.Remove(node.elem)
+		node.segment = segProtected
+		node.elem = p.protected.PushFront(key)
+
+		if p.protected.Len() > p.protectedCap {
+			demoted := p.protected.Back().Value.(string)
+			p.protected.Remove(p.protected.Back())
+			p.nodes[demoted] = &tinyLFUNode{segment: segProbation, elem: p.probation.PushFront(demoted)}
+		}
+	}
+}
+
+// evict жертвует сначала основной областью, как при отказе в допуске
+func (p *tinyLFUPolicy) evict() (string, bool) {
+	for _, l := range []*list.List{p.probation, p.window, p.protected} {
+		if elem := l.Back(); elem != nil {
+			key := elem.Value.(string)
+			l.Remove(elem)
+			delete(p.nodes, key)
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func (p *tinyLFUPolicy) remove(key string) {
+	node, ok := p.nodes[key]
+	if !ok {
+		return
+	}
+
+	switch node.segment {
+	case segWindow:
+		p.window.Remove(node.elem)
+	case segProbation:
+		p.probation.Remove(node.elem)
+	case segProtected:
+		p.protected.Remove(node.elem)
+	}
+	delete(p.nodes, key)
+}
+
+// countMinSketch - 4 строки 4-битных счётчиков (хранятся в байтах).
+// После 10*capacity инкрементов все счётчики делятся пополам,
+// чтобы популярность, набранная давно, со временем забывалась.
+type countMinSketch struct {
+	rows      [4][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+func newCountMinSketch(capacity int) *countMinSketch {
+	width := 1 << bits.Len(uint(max(capacity, 16)-1))
+
+	s := &countMinSketch{
+		mask:    uint64(width - 1),
+		resetAt: 10 * max(capacity, 16),
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *countMinSketch) indexes(key string) [4]uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+
+	// двойное хэширование: i-й индекс = h1 + i*h2
+	h1, h2 := sum&0xffffffff, sum>>32|1
+	var idx [4]uint64
+	for i := range idx {
+		idx[i] = (h1 + uint64(i)*h2) & s.mask
+	}
+	return idx
+}
+
+func (s *countMinSketch) increment(key string) {
+	for i, j := range s.indexes(key) {
+		if s.rows[i][j] < 15 {
+			s.rows[i][j]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+func (s *countMinSketch) estimate(key string) uint8 {
+	est := uint8(15)
+	for i, j := range s.indexes(key) {
+		est = min(est, s.rows[i][j])
+	}
+	return est
+}
+
+func (s *countMinSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] /= 2
+		}
+	}
+	s.additions /= 2
+}
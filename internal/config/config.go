@@ -1,26 +1,59 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DB     DBConfig
-	Kafka  KafkaConfig
-	Server ServerConfig
-	Cache  CacheConfig
+	DB        DBConfig
+	Kafka     KafkaConfig
+	Server    ServerConfig
+	Cache     CacheConfig
+	Redis     RedisConfig
+	Partition PartitionConfig
+	Audit     AuditConfig
+	Shard     ShardConfig
+	PII       PIIConfig
 }
 
 type DBConfig struct {
+	// полная строка подключения, если задана - Host/Port/User/Password/Name не используются
+	URL      string
 	Host     string
 	Port     int
 	User     string
 	Password string
 	Name     string
+
+	// sslmode/sslrootcert/statement_timeout/application_name, при URL не применяются
+	SSLMode          string
+	SSLRootCert      string
+	StatementTimeout time.Duration
+	ApplicationName  string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ожидание БД при старте: попытки ping с удвоением паузы
+	PingTimeout    time.Duration
+	ConnectRetries int
+	ConnectBackoff time.Duration
+
+	// host[:port] реплик для чтения, остальные параметры как у primary
+	Replicas             []string
+	ReplicaMaxLag        time.Duration
+	ReplicaCheckInterval time.Duration
+
+	// запросы дольше порога пишутся в лог, 0 - не писать
+	SlowQueryThreshold time.Duration
 }
 
 type KafkaConfig struct {
@@ -31,10 +64,74 @@ type KafkaConfig struct {
 
 type ServerConfig struct {
 	Port string
+	// токен для /admin, пустой - админские ручки отключены
+	AdminToken string
 }
 
 type CacheConfig struct {
 	Size int
+	// lru, lfu, arc или tinylfu
+	Policy string
+	// бюджет по оценке объёма заказов в байтах, 0 - ограничение только по Size
+	MaxBytes int64
+	// число независимых LRU, 1 - один общий
+	Shards int
+	// срок жизни записи, 0 - только вытеснение по размеру
+	TTL             time.Duration
+	JanitorInterval time.Duration
+	// период сводки по кэшу в логе, 0 - не писать
+	StatsInterval time.Duration
+
+	// снимок кэша при остановке, пустой путь - прогрев из БД
+	SnapshotFile   string
+	SnapshotMaxAge time.Duration
+
+	// кэш отсутствующих order_uid, NegativeSize 0 - выключен
+	NegativeSize int
+	NegativeTTL  time.Duration
+}
+
+// RedisConfig - кэш второго уровня, пустой Addr отключает его
+type RedisConfig struct {
+	Addr      string
+	Password  string
+	DB        int
+	KeyPrefix string
+	TTL       time.Duration
+	// на одну операцию; при превышении запрос идёт в БД
+	Timeout time.Duration
+}
+
+type PartitionConfig struct {
+	// сколько месяцев вперёд держать созданными
+	Premake int
+	// через сколько месяцев партиция уходит в архив, 0 - хранить всегда
+	RetentionMonths int
+	ArchiveDir      string
+}
+
+// ShardConfig - пустой Shards означает работу с одной БД из DBConfig
+type ShardConfig struct {
+	Shards []Shard
+	// shardkey заказа -> имя шарда; ключи вне карты распределяются хэшем
+	Map map[string]string
+}
+
+type Shard struct {
+	Name string
+	URL  string
+}
+
+// PIIConfig - пустой KeyFile отключает шифрование ПДн доставки
+type PIIConfig struct {
+	KeyFile           string
+	ReencryptInterval time.Duration
+	ReencryptBatch    int
+}
+
+type AuditConfig struct {
+	// сколько хранить исходные сообщения, 0 - бессрочно
+	Retention time.Duration
 }
 
 func LoadConfig() (*Config, error) {
@@ -44,6 +141,7 @@ func LoadConfig() (*Config, error) {
 
 	var err error
 
+	cfg.DB.URL = getEnv("DATABASE_URL", "")
 	cfg.DB.Host = getEnv("DB_HOST", "localhost")
 	cfg.DB.Port, err = getEnvAsInt("DB_PORT", 5432)
 	if err != nil {
@@ -53,17 +151,168 @@ func LoadConfig() (*Config, error) {
 	cfg.DB.Password = getEnv("DB_PASSWORD", "")
 	cfg.DB.Name = getEnv("DB_NAME", "orders")
 
+	cfg.DB.SSLMode = getEnv("DB_SSLMODE", "disable")
+	cfg.DB.SSLRootCert = getEnv("DB_SSLROOTCERT", "")
+	cfg.DB.StatementTimeout, err = getEnvAsDuration("DB_STATEMENT_TIMEOUT", 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DB.ApplicationName = getEnv("DB_APPLICATION_NAME", "order_service")
+
+	cfg.DB.MaxOpenConns, err = getEnvAsInt("DB_MAX_OPEN_CONNS", 25)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DB.MaxIdleConns, err = getEnvAsInt("DB_MAX_IDLE_CONNS", 25)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DB.ConnMaxLifetime, err = getEnvAsDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DB.ConnMaxIdleTime, err = getEnvAsDuration("DB_CONN_MAX_IDLE_TIME", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.DB.PingTimeout, err = getEnvAsDuration("DB_PING_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DB.ConnectRetries, err = getEnvAsInt("DB_CONNECT_RETRIES", 10)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DB.ConnectBackoff, err = getEnvAsDuration("DB_CONNECT_BACKOFF", time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	if replicas := getEnv("DB_REPLICAS", ""); replicas != "" {
+		cfg.DB.Replicas = strings.Split(replicas, ",")
+	}
+	cfg.DB.ReplicaMaxLag, err = getEnvAsDuration("DB_REPLICA_MAX_LAG", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DB.ReplicaCheckInterval, err = getEnvAsPositiveDuration("DB_REPLICA_CHECK_INTERVAL", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.DB.SlowQueryThreshold, err = getEnvAsDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+
 	brokers := getEnv("KAFKA_BROKERS", "localgost:9092")
 	cfg.Kafka.Brokers = strings.Split(brokers, ",")
 	cfg.Kafka.Topic = getEnv("KAFKA_TOPIC", "orders")
 	cfg.Kafka.GroupID = getEnv("KAFKA_GROUP", "order_service")
 
 	cfg.Server.Port = getEnv("SERVER_PORT", "8080")
+	cfg.Server.AdminToken = getEnv("ADMIN_TOKEN", "")
 
 	cfg.Cache.Size, err = getEnvAsInt("CACHE_SIZE", 100)
 	if err != nil {
 		return nil, err
 	}
+	maxBytes, err := getEnvAsInt("CACHE_MAX_BYTES", 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Cache.MaxBytes = int64(maxBytes)
+	cfg.Cache.Policy = getEnv("CACHE_POLICY", "lru")
+	cfg.Cache.Shards, err = getEnvAsInt("CACHE_SHARDS", 1)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Cache.TTL, err = getEnvAsDuration("CACHE_TTL", 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Cache.JanitorInterval, err = getEnvAsPositiveDuration("CACHE_JANITOR_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Cache.StatsInterval, err = getEnvAsDuration("CACHE_STATS_INTERVAL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Cache.SnapshotFile = getEnv("CACHE_SNAPSHOT_FILE", "")
+	cfg.Cache.SnapshotMaxAge, err = getEnvAsDuration("CACHE_SNAPSHOT_MAX_AGE", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Cache.NegativeSize, err = getEnvAsInt("CACHE_NEGATIVE_SIZE", 10000)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Cache.NegativeTTL, err = getEnvAsDuration("CACHE_NEGATIVE_TTL", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Redis.Addr = getEnv("REDIS_ADDR", "")
+	cfg.Redis.Password = getEnv("REDIS_PASSWORD", "")
+	cfg.Redis.DB, err = getEnvAsInt("REDIS_DB", 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Redis.KeyPrefix = getEnv("REDIS_KEY_PREFIX", "order:")
+	cfg.Redis.TTL, err = getEnvAsDuration("REDIS_TTL", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Redis.Timeout, err = getEnvAsDuration("REDIS_TIMEOUT", 100*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Partition.Premake, err = getEnvAsInt("PARTITION_PREMAKE", 3)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Partition.RetentionMonths, err = getEnvAsInt("PARTITION_RETENTION_MONTHS", 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Partition.ArchiveDir = getEnv("PARTITION_ARCHIVE_DIR", "archive")
+
+	cfg.Audit.Retention, err = getEnvAsDuration("RAW_MESSAGES_RETENTION", 90*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.PII.KeyFile = getEnv("PII_KEY_FILE", "")
+	cfg.PII.ReencryptInterval, err = getEnvAsPositiveDuration("PII_REENCRYPT_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PII.ReencryptBatch, err = getEnvAsInt("PII_REENCRYPT_BATCH", 500)
+	if err != nil {
+		return nil, err
+	}
+
+	// SHARDS=s0=postgres://...,s1=postgres://...
+	shards, err := getEnvAsPairs("SHARDS")
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range shards {
+		cfg.Shard.Shards = append(cfg.Shard.Shards, Shard{Name: pair[0], URL: pair[1]})
+	}
+
+	// SHARD_MAP=0=s0,1=s0,2=s1
+	shardMap, err := getEnvAsPairs("SHARD_MAP")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Shard.Map = make(map[string]string, len(shardMap))
+	for _, pair := range shardMap {
+		cfg.Shard.Map[pair[0]] = pair[1]
+	}
 
 	return cfg, nil
 }
@@ -85,3 +334,41 @@ func getEnvAsInt(key string, defaultValue int) (int, error) {
 	}
 	return defaultValue, nil
 }
+
+func getEnvAsDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	if val := os.Getenv(key); val != "" {
+		return time.ParseDuration(val)
+	}
+	return defaultValue, nil
+}
+
+// getEnvAsPositiveDuration - для интервалов тикеров: time.NewTicker паникует на нуле
+func getEnvAsPositiveDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	d, err := getEnvAsDuration(key, defaultValue)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive, got %s", key, d)
+	}
+	return d, nil
+}
+
+// getEnvAsPairs разбирает список key=value через запятую, значение может содержать '='
+func getEnvAsPairs(key string) ([][2]string, error) {
+	val := os.Getenv(key)
+	if val == "" {
+		return nil, nil
+	}
+
+	var pairs [][2]string
+	for _, item := range strings.Split(val, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || k == "" || v == "" {
+			return nil, fmt.Errorf("%s: invalid entry %q, expected key=value", key, item)
+		}
+		pairs = append(pairs, [2]string{k, v})
+	}
+
+	return pairs, nil
+}
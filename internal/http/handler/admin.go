@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/torrentxok/order_service/internal/service"
+	"go.uber.org/zap"
+)
+
+type AdminHandler struct {
+	audit  *service.AuditService
+	gdpr   *service.GDPRService
+	logger *zap.Logger
+}
+
+func NewAdminHandler(audit *service.AuditService, gdpr *service.GDPRService, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		audit:  audit,
+		gdpr:   gdpr,
+		logger: logger,
+	}
+}
+
+// GetRawMessages отдаёт все полученные сообщения по заказу, value и key - base64
+func (h *AdminHandler) GetRawMessages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orderUID := chi.URLParam(r, "order_uid")
+	if orderUID == "" {
+		http.Error(w, "order_uid is required", http.StatusBadRequest)
+		return
+	}
+
+	msgs, err := h.audit.GetRawMessages(ctx, orderUID)
+	if err != nil {
+		if errors.Is(err, service.ErrOrderNotFound) {
+			http.Error(w, "raw messages not found", http.StatusNotFound)
+			return
+		}
+
+		h.logger.Error("failed to get raw messages", zap.Error(err))
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(msgs)
+}
+
+// ExportCustomerData отдаёт все заказы покупателя одним JSON-файлом
+func (h *AdminHandler) ExportCustomerData(w http.ResponseWriter, r *http.Request) {
+	customerID := chi.URLParam(r, "customer_id")
+
+	bundle, err := h.gdpr.ExportCustomerData(r.Context(), customerID, operator(r))
+	if err != nil {
+		h.writeError(w, "failed to export customer data", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="customer-data.json"`)
+	json.NewEncoder(w).Encode(bundle)
+}
+
+func (h *AdminHandler) EraseCustomerData(w http.ResponseWriter, r *http.Request) {
+	customerID := chi.URLParam(r, "customer_id")
+
+	affected, err := h.gdpr.EraseCustomerData(r.Context(), customerID, operator(r))
+	if err != nil {
+		h.writeError(w, "failed to erase customer data", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"orders_affected": affected})
+}
+
+func (h *AdminHandler) writeError(w http.ResponseWriter, msg string, err error) {
+	if errors.Is(err, service.ErrInvalidArgument) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Error(msg, zap.Error(err))
+	writeServiceError(w, err)
+}
+
+// operator - кто выполнил запрос, для журнала
+func operator(r *http.Request) string {
+	if op := r.Header.Get("X-Operator"); op != "" {
+		return op
+	}
+	return "admin"
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/torrentxok/order_service/internal/service"
+	"go.uber.org/zap"
+)
+
+type CacheHandler struct {
+	admin  *service.CacheAdminService
+	logger *zap.Logger
+}
+
+func NewCacheHandler(admin *service.CacheAdminService, logger *zap.Logger) *CacheHandler {
+	return &CacheHandler{
+		admin:  admin,
+		logger: logger,
+	}
+}
+
+func (h *CacheHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.admin.Stats())
+}
+
+func (h *CacheHandler) Keys(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.admin.Keys())
+}
+
+func (h *CacheHandler) Inspect(w http.ResponseWriter, r *http.Request) {
+	entry, err := h.admin.Inspect(chi.URLParam(r, "order_uid"))
+	if err != nil {
+		if errors.Is(err, service.ErrOrderNotFound) {
+			http.Error(w, "order is not cached", http.StatusNotFound)
+			return
+		}
+
+		h.logger.Error("failed to inspect cache entry", zap.Error(err))
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, entry)
+}
+
+func (h *CacheHandler) Evict(w http.ResponseWriter, r *http.Request) {
+	if err := h.admin.Evict(r.Context(), chi.URLParam(r, "order_uid"), operator(r)); err != nil {
+		h.logger.Error("failed to broadcast cache eviction", zap.Error(err))
+		http.Error(w, "evicted on this instance only, other instances were not notified", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CacheHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	if err := h.admin.Purge(r.Context(), operator(r)); err != nil {
+		h.logger.Error("failed to broadcast cache purge", zap.Error(err))
+		http.Error(w, "purged on this instance only, other instances were not notified", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CacheHandler) Warmup(w http.ResponseWriter, r *http.Request) {
+	if err := h.admin.Warmup(r.Context(), operator(r)); err != nil {
+		h.logger.Error("failed to re-warm cache", zap.Error(err))
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, h.admin.Stats())
+}
+
+// Resize принимает {"capacity": N}
+func (h *CacheHandler) Resize(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Capacity int `json:"capacity"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.admin.Resize(req.Capacity, operator(r)); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidArgument):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrResizeUnsupported):
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+		default:
+			h.logger.Error("failed to resize cache", zap.Error(err))
+			writeServiceError(w, err)
+		}
+		return
+	}
+
+	writeJSON(w, h.admin.Stats())
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/torrentxok/order_service/internal/service"
+)
+
+// writeServiceError отвечает статусом, соответствующим ошибке хранилища
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrTimeout):
+		http.Error(w, "timeout", http.StatusGatewayTimeout)
+	case errors.Is(err, service.ErrUnavailable), errors.Is(err, service.ErrRetryable):
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
@@ -39,7 +39,7 @@ func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 		}
 
 		h.logger.Error("failed to get order", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
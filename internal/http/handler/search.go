@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/service"
+	"go.uber.org/zap"
+)
+
+type SearchHandler struct {
+	service *service.SearchService
+	logger  *zap.Logger
+}
+
+func NewSearchHandler(service *service.SearchService, logger *zap.Logger) *SearchHandler {
+	return &SearchHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Search: GET /admin/search?q=vivienne sabo mascara&name=...&phone=...&email=...&from=...&to=...&limit=...
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	q := models.SearchQuery{
+		Text:  params.Get("q"),
+		Name:  params.Get("name"),
+		Phone: params.Get("phone"),
+		Email: params.Get("email"),
+	}
+
+	var err error
+	if q.From, err = parseTimeParam(params.Get("from")); err != nil {
+		http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.To, err = parseTimeParam(params.Get("to")); err != nil {
+		http.Error(w, "invalid to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := h.service.Search(r.Context(), q)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidArgument) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		h.logger.Error("failed to search orders", zap.Error(err))
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
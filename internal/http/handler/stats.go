@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/service"
+	"go.uber.org/zap"
+)
+
+type StatsHandler struct {
+	service *service.StatsService
+	logger  *zap.Logger
+}
+
+func NewStatsHandler(service *service.StatsService, logger *zap.Logger) *StatsHandler {
+	return &StatsHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *StatsHandler) Revenue(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.parseFilter(w, r)
+	if !ok {
+		return
+	}
+
+	points, err := h.service.Revenue(r.Context(), f)
+	h.respond(w, points, err)
+}
+
+func (h *StatsHandler) Basket(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.parseFilter(w, r)
+	if !ok {
+		return
+	}
+
+	points, err := h.service.Basket(r.Context(), f)
+	h.respond(w, points, err)
+}
+
+func (h *StatsHandler) TopBrands(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.parseFilter(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.TopBrands(r.Context(), f)
+	h.respond(w, stats, err)
+}
+
+func (h *StatsHandler) DeliveryServices(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.parseFilter(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.DeliveryServices(r.Context(), f)
+	h.respond(w, stats, err)
+}
+
+func (h *StatsHandler) Payments(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.parseFilter(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.Payments(r.Context(), f, r.URL.Query().Get("by"))
+	h.respond(w, stats, err)
+}
+
+func (h *StatsHandler) parseFilter(w http.ResponseWriter, r *http.Request) (models.StatsFilter, bool) {
+	q := r.URL.Query()
+
+	var f models.StatsFilter
+	var err error
+
+	if f.From, err = parseTimeParam(q.Get("from")); err != nil {
+		http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+		return f, false
+	}
+	if f.To, err = parseTimeParam(q.Get("to")); err != nil {
+		http.Error(w, "invalid to: "+err.Error(), http.StatusBadRequest)
+		return f, false
+	}
+
+	f.GroupBy = q.Get("group_by")
+
+	if limit := q.Get("limit"); limit != "" {
+		if f.Limit, err = strconv.Atoi(limit); err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return f, false
+		}
+	}
+
+	return f, true
+}
+
+func (h *StatsHandler) respond(w http.ResponseWriter, data any, err error) {
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidArgument) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		h.logger.Error("failed to get stats", zap.Error(err))
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+// parseTimeParam принимает RFC3339 или дату YYYY-MM-DD (UTC)
+func parseTimeParam(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("expected RFC3339 or YYYY-MM-DD, got %q", s)
+}
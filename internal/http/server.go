@@ -8,6 +8,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/torrentxok/order_service/internal/http/handler"
 	"go.uber.org/zap"
 )
@@ -17,7 +18,15 @@ type Server struct {
 	logger     *zap.Logger
 }
 
-func NewServer(addr string, orderhandler *handler.OrderHandler, logger *zap.Logger) *Server {
+type Handlers struct {
+	Order  *handler.OrderHandler
+	Admin  *handler.AdminHandler
+	Stats  *handler.StatsHandler
+	Search *handler.SearchHandler
+	Cache  *handler.CacheHandler
+}
+
+func NewServer(addr string, handlers Handlers, adminToken string, logger *zap.Logger) *Server {
 	r := chi.NewRouter()
 
 	// базовые middleware
@@ -29,7 +38,7 @@ func NewServer(addr string, orderhandler *handler.OrderHandler, logger *zap.Logg
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"}, // для тестового задания — ок
 		AllowedMethods:   []string{"GET", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Content-Type"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Operator"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
 		MaxAge:           300,
@@ -38,9 +47,51 @@ func NewServer(addr string, orderhandler *handler.OrderHandler, logger *zap.Logg
 	r.Use(middleware.Logger)
 
 	r.Route("/order", func(r chi.Router) {
-		r.Get("/{order_uid}", orderhandler.GetOrder)
+		r.Get("/{order_uid}", handlers.Order.GetOrder)
 	})
 
+	r.Handle("/metrics", promhttp.Handler())
+
+	if adminToken != "" {
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(adminAuth(adminToken))
+
+			r.Get("/order/{order_uid}/raw", handlers.Admin.GetRawMessages)
+
+			// nil - возможность выключена конфигурацией (например, шардированием)
+			if handlers.Stats != nil {
+				// выручка и разбивки по брендам и платёжным системам - коммерческие данные
+				r.Route("/stats", func(r chi.Router) {
+					r.Get("/revenue", handlers.Stats.Revenue)
+					r.Get("/basket", handlers.Stats.Basket)
+					r.Get("/brands", handlers.Stats.TopBrands)
+					r.Get("/delivery-services", handlers.Stats.DeliveryServices)
+					r.Get("/payments", handlers.Stats.Payments)
+				})
+			}
+
+			// поиск отдаёт ПДн получателей и ищет по телефону и email
+			if handlers.Search != nil {
+				r.Get("/search", handlers.Search.Search)
+			}
+
+			r.Get("/customers/{customer_id}/export", handlers.Admin.ExportCustomerData)
+			r.Post("/customers/{customer_id}/erase", handlers.Admin.EraseCustomerData)
+
+			r.Route("/cache", func(r chi.Router) {
+				r.Get("/", handlers.Cache.Stats)
+				r.Get("/keys", handlers.Cache.Keys)
+				r.Get("/keys/{order_uid}", handlers.Cache.Inspect)
+				r.Delete("/keys/{order_uid}", handlers.Cache.Evict)
+				r.Post("/purge", handlers.Cache.Purge)
+				r.Post("/warmup", handlers.Cache.Warmup)
+				r.Post("/resize", handlers.Cache.Resize)
+			})
+		})
+	} else {
+		logger.Warn("ADMIN_TOKEN is not set, admin endpoints disabled")
+	}
+
 	httpServer := &http.Server{
 		Addr:    addr,
 		Handler: r,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 	"github.com/torrentxok/order_service/internal/models"
@@ -14,13 +15,15 @@ import (
 type Consumer struct {
 	reader  *kafka.Reader
 	service *service.OrderService
+	audit   *service.AuditService
 	logger  *zap.Logger
 }
 
-func NewConsumer(reader *kafka.Reader, svc *service.OrderService, logger *zap.Logger) *Consumer {
+func NewConsumer(reader *kafka.Reader, svc *service.OrderService, audit *service.AuditService, logger *zap.Logger) *Consumer {
 	return &Consumer{
 		reader:  reader,
 		service: svc,
+		audit:   audit,
 		logger:  logger,
 	}
 }
@@ -53,17 +56,84 @@ func (c *Consumer) Run(ctx context.Context) error {
 }
 
 func (c *Consumer) handleMessage(ctx context.Context, msg kafka.Message) error {
+	raw := newRawMessage(msg)
 
 	var order models.Order
 	if err := json.Unmarshal(msg.Value, &order); err != nil {
 		c.logger.Warn("failed to unmarshal message", zap.Error(err))
+		c.saveRaw(ctx, raw)
 		return err
 	}
 
+	if order.OrderUID != "" {
+		raw.OrderUID = &order.OrderUID
+	}
+	c.saveRaw(ctx, raw)
+
 	if err := order.Validate(); err != nil {
 		c.logger.Warn("order validation failed", zap.Error(err))
 		return err
 	}
 
-	return c.service.CreateOrder(ctx, &order)
+	return c.createOrder(ctx, &order)
+}
+
+const (
+	maxCreateAttempts = 5
+	retryBaseDelay    = 500 * time.Millisecond
+)
+
+// createOrder повторяет временные ошибки БД (deadlock, недоступность) с экспоненциальной задержкой
+func (c *Consumer) createOrder(ctx context.Context, order *models.Order) error {
+	delay := retryBaseDelay
+
+	for attempt := 1; ; attempt++ {
+		err := c.service.CreateOrder(ctx, order)
+		if err == nil || !service.IsTemporary(err) || attempt == maxCreateAttempts {
+			return err
+		}
+
+		c.logger.Warn("temporary error, retrying",
+			zap.String("order_uid", order.OrderUID),
+			zap.Int("attempt", attempt),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+	}
+}
+
+// ошибка аудита не должна мешать обработке заказа
+func (c *Consumer) saveRaw(ctx context.Context, raw *models.RawMessage) {
+	if c.audit == nil {
+		return
+	}
+
+	if err := c.audit.SaveRawMessage(ctx, raw); err != nil {
+		c.logger.Error("failed to save raw message", zap.Error(err))
+	}
+}
+
+func newRawMessage(msg kafka.Message) *models.RawMessage {
+	headers := make(models.MessageHeaders, 0, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers = append(headers, models.MessageHeader{Key: h.Key, Value: h.Value})
+	}
+
+	return &models.RawMessage{
+		Topic:      msg.Topic,
+		Partition:  msg.Partition,
+		Offset:     msg.Offset,
+		Key:        msg.Key,
+		Headers:    headers,
+		Value:      msg.Value,
+		ReceivedAt: time.Now(),
+	}
 }
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/torrentxok/order_service/internal/cache"
+)
+
+// RegisterCache экспортирует cache.Stats с меткой tier; значения читаются при каждом scrape
+func RegisterCache(reg prometheus.Registerer, tier string, c cache.OrderCache) error {
+	labels := prometheus.Labels{"tier": tier}
+
+	counter := func(name, help string, value func(cache.Stats) uint64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   "cache",
+			Name:        name,
+			Help:        help,
+			ConstLabels: labels,
+		}, func() float64 { return float64(value(c.Stats())) })
+	}
+	gauge := func(name, help string, value func(cache.Stats) float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "cache",
+			Name:        name,
+			Help:        help,
+			ConstLabels: labels,
+		}, func() float64 { return value(c.Stats()) })
+	}
+
+	collectors := []prometheus.Collector{
+		counter("hits_total", "Order cache hits.", func(s cache.Stats) uint64 { return s.Hits }),
+		counter("misses_total", "Order cache misses, expired entries included.", func(s cache.Stats) uint64 { return s.Misses }),
+		counter("evictions_total", "Entries evicted by capacity.", func(s cache.Stats) uint64 { return s.Evictions }),
+		counter("expirations_total", "Entries removed after TTL.", func(s cache.Stats) uint64 { return s.Expirations }),
+		counter("errors_total", "Failed calls to an external cache tier.", func(s cache.Stats) uint64 { return s.Errors }),
+		gauge("entries", "Current number of cached orders.", func(s cache.Stats) float64 { return float64(s.Len) }),
+		gauge("capacity", "Configured cache capacity.", func(s cache.Stats) float64 { return float64(s.Capacity) }),
+		gauge("bytes", "Estimated memory used by cached orders.", func(s cache.Stats) float64 { return float64(s.Bytes) }),
+		gauge("max_bytes", "Configured byte budget, 0 if unlimited.", func(s cache.Stats) float64 { return float64(s.MaxBytes) }),
+	}
+
+	for _, col := range collectors {
+		if err := reg.Register(col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
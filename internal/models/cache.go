@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// CacheEntry - запись кэша заказов для админки
+type CacheEntry struct {
+	OrderUID   string     `json:"order_uid"`
+	StoredAt   *time.Time `json:"stored_at,omitempty"`
+	AgeSeconds float64    `json:"age_seconds,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Order      *Order     `json:"order,omitempty"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+const (
+	IssueMissingDelivery    = "missing_delivery"
+	IssueMissingPayment     = "missing_payment"
+	IssueNoItems            = "no_items"
+	IssueTrackMismatch      = "track_number_mismatch"
+	IssueGoodsTotalMismatch = "goods_total_mismatch"
+)
+
+// Inconsistency - найденное нарушение целостности заказа
+type Inconsistency struct {
+	OrderUID    string    `json:"order_uid"`
+	DateCreated time.Time `json:"date_created"`
+	Kind        string    `json:"kind"`
+	Detail      string    `json:"detail,omitempty"`
+	Repaired    bool      `json:"repaired"`
+}
+
+// Incomplete - заказ без обязательных строк; такой можно удалить и принять заново
+func (i Inconsistency) Incomplete() bool {
+	switch i.Kind {
+	case IssueMissingDelivery, IssueMissingPayment, IssueNoItems:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	DataRequestExport = "export"
+	DataRequestErase  = "erase"
+)
+
+// DataRequest - запись журнала запросов субъектов персональных данных
+type DataRequest struct {
+	ID             int64     `db:"id" json:"id"`
+	CustomerID     string    `db:"customer_id" json:"customer_id"`
+	Type           string    `db:"request_type" json:"request_type"`
+	RequestedBy    string    `db:"requested_by" json:"requested_by"`
+	OrdersAffected int       `db:"orders_affected" json:"orders_affected"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
+
+// CustomerDataBundle - машиночитаемая выгрузка всех данных покупателя
+type CustomerDataBundle struct {
+	CustomerID  string    `json:"customer_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Orders      []*Order  `json:"orders"`
+}
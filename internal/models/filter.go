@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// OrderFilter - выборка заказов для пакетных операций, пустые поля не ограничивают
+type OrderFilter struct {
+	From       time.Time
+	To         time.Time
+	CustomerID string
+}
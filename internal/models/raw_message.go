@@ -0,0 +1,50 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// RawMessage - сообщение из Kafka в том виде, в котором оно пришло
+type RawMessage struct {
+	ID         int64          `db:"id" json:"id"`
+	OrderUID   *string        `db:"order_uid" json:"order_uid"`
+	Topic      string         `db:"topic" json:"topic"`
+	Partition  int            `db:"kafka_partition" json:"partition"`
+	Offset     int64          `db:"kafka_offset" json:"offset"`
+	Key        []byte         `db:"key" json:"key"`
+	Headers    MessageHeaders `db:"headers" json:"headers"`
+	Value      []byte         `db:"value" json:"value"`
+	ReceivedAt time.Time      `db:"received_at" json:"received_at"`
+}
+
+type MessageHeader struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// MessageHeaders хранится в jsonb
+type MessageHeaders []MessageHeader
+
+func (h MessageHeaders) Value() (driver.Value, error) {
+	if h == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(h)
+}
+
+func (h *MessageHeaders) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*h = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, h)
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	default:
+		return errors.New("unsupported headers type")
+	}
+}
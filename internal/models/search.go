@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// SearchQuery - Text ищется по товарам (бренд, название), Name - по получателю,
+// Phone и Email - точное совпадение
+type SearchQuery struct {
+	Text  string
+	Name  string
+	Phone string
+	Email string
+	From  time.Time
+	To    time.Time
+	Limit int
+}
+
+type SearchResult struct {
+	OrderUID     string    `json:"order_uid"`
+	DateCreated  time.Time `json:"date_created"`
+	DeliveryName string    `json:"delivery_name"`
+	Rank         float64   `json:"rank"`
+	// фрагменты совпавших товаров, совпадения обёрнуты в <mark></mark>
+	Snippets []string `json:"snippets"`
+}
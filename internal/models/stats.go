@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// StatsFilter - общий фильтр аналитических запросов, интервал [From, To)
+type StatsFilter struct {
+	From    time.Time
+	To      time.Time
+	GroupBy string // day, week, month
+	Limit   int
+}
+
+type RevenuePoint struct {
+	Bucket   time.Time `db:"bucket" json:"bucket"`
+	Currency string    `db:"currency" json:"currency"`
+	Revenue  int64     `db:"revenue" json:"revenue"`
+	Orders   int64     `db:"orders" json:"orders"`
+}
+
+type BasketPoint struct {
+	Bucket        time.Time `db:"bucket" json:"bucket"`
+	Orders        int64     `db:"orders" json:"orders"`
+	AvgItems      float64   `db:"avg_items" json:"avg_items"`
+	AvgGoodsTotal float64   `db:"avg_goods_total" json:"avg_goods_total"`
+}
+
+type BrandStat struct {
+	Brand   string `db:"brand" json:"brand"`
+	Revenue int64  `db:"revenue" json:"revenue"`
+	Items   int64  `db:"items" json:"items"`
+}
+
+// BreakdownStat - количество и сумма заказов по значению одного измерения
+type BreakdownStat struct {
+	Key    string `db:"key" json:"key"`
+	Orders int64  `db:"orders" json:"orders"`
+	Amount int64  `db:"amount" json:"amount"`
+}
@@ -0,0 +1,192 @@
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Cipher реализует envelope-шифрование: поля строки шифруются своим ключом (DEK),
+// а DEK хранится рядом, зашифрованный текущим ключом провайдера (KEK)
+type Cipher struct {
+	keys KeyProvider
+}
+
+func NewCipher(keys KeyProvider) *Cipher {
+	return &Cipher{keys: keys}
+}
+
+// Sealed - зашифрованные поля и всё, что нужно для их расшифровки
+type Sealed struct {
+	KeyID      string
+	WrappedDEK []byte
+	Fields     []string
+}
+
+func (c *Cipher) CurrentKeyID() string {
+	return c.keys.CurrentKeyID()
+}
+
+// RetiredKeyIDs - доступные ключи, кроме текущего: строки под ними нужно перешифровать
+func (c *Cipher) RetiredKeyIDs() []string {
+	current := c.keys.CurrentKeyID()
+
+	var ids []string
+	for _, id := range c.keys.KeyIDs() {
+		if id != current {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (c *Cipher) Seal(fields ...string) (Sealed, error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return Sealed{}, err
+	}
+
+	keyID := c.keys.CurrentKeyID()
+	wrapped, err := c.wrap(keyID, dek)
+	if err != nil {
+		return Sealed{}, err
+	}
+
+	sealed := Sealed{KeyID: keyID, WrappedDEK: wrapped, Fields: make([]string, len(fields))}
+	for i, field := range fields {
+		ct, err := seal(dek, []byte(field), []byte{byte(i)})
+		if err != nil {
+			return Sealed{}, err
+		}
+		sealed.Fields[i] = base64.StdEncoding.EncodeToString(ct)
+	}
+
+	return sealed, nil
+}
+
+// Open расшифровывает поля в том же порядке, в котором они передавались в Seal
+func (c *Cipher) Open(keyID string, wrappedDEK []byte, fields ...string) ([]string, error) {
+	dek, err := c.unwrap(keyID, wrappedDEK)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := make([]string, len(fields))
+	for i, field := range fields {
+		ct, err := base64.StdEncoding.DecodeString(field)
+		if err != nil {
+			return nil, err
+		}
+
+		pt, err := open(dek, ct, []byte{byte(i)})
+		if err != nil {
+			return nil, err
+		}
+		plain[i] = string(pt)
+	}
+
+	return plain, nil
+}
+
+// Rewrap перешифровывает только DEK текущим ключом, сами поля не меняются
+func (c *Cipher) Rewrap(keyID string, wrappedDEK []byte) (string, []byte, error) {
+	dek, err := c.unwrap(keyID, wrappedDEK)
+	if err != nil {
+		return "", nil, err
+	}
+
+	current := c.keys.CurrentKeyID()
+	wrapped, err := c.wrap(current, dek)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return current, wrapped, nil
+}
+
+// BlindIndex - HMAC нормализованного значения для точного поиска по зашифрованному полю
+func (c *Cipher) BlindIndex(kind, value string) string {
+	mac := hmac.New(sha256.New, c.keys.IndexKey())
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(normalize(kind, value)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func normalize(kind, value string) string {
+	value = strings.TrimSpace(value)
+
+	switch kind {
+	case "phone":
+		return strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) || r == '+' {
+				return r
+			}
+			return -1
+		}, value)
+	case "email":
+		return strings.ToLower(value)
+	default:
+		return value
+	}
+}
+
+func (c *Cipher) wrap(keyID string, dek []byte) ([]byte, error) {
+	kek, err := c.keys.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return seal(kek, dek, []byte(keyID))
+}
+
+func (c *Cipher) unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	kek, err := c.keys.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return open(kek, wrapped, []byte(keyID))
+}
+
+// seal возвращает nonce || ciphertext
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(key, data, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ct := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ct, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
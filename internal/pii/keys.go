@@ -0,0 +1,109 @@
+package pii
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// KeyProvider отдаёт ключи шифрования ключей (KEK) по идентификатору.
+// Ключ blind-индекса не ротируется: иначе перестанет работать поиск по старым строкам.
+type KeyProvider interface {
+	CurrentKeyID() string
+	Key(id string) ([]byte, error)
+	// KeyIDs - все ключи, которыми можно расшифровать, включая текущий
+	KeyIDs() []string
+	IndexKey() []byte
+}
+
+// FileKeyProvider - ключи из локального JSON-файла, для разработки:
+//
+//	{"current": "k2", "keys": {"k1": "<base64>", "k2": "<base64>"}, "index_key": "<base64>"}
+//
+// Для ротации добавьте новый ключ, сделайте его current и перезапустите сервис,
+// старые строки перешифрует фоновая задача.
+type FileKeyProvider struct {
+	current  string
+	keys     map[string][]byte
+	indexKey []byte
+}
+
+func LoadFileKeyProvider(path string) (*FileKeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Current  string            `json:"current"`
+		Keys     map[string]string `json:"keys"`
+		IndexKey string            `json:"index_key"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse key file: %w", err)
+	}
+
+	p := &FileKeyProvider{
+		current: file.Current,
+		keys:    make(map[string][]byte, len(file.Keys)),
+	}
+
+	for id, encoded := range file.Keys {
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", id, err)
+		}
+		p.keys[id] = key
+	}
+
+	if _, ok := p.keys[p.current]; !ok {
+		return nil, fmt.Errorf("current key %q is not in keys", p.current)
+	}
+
+	if p.indexKey, err = decodeKey(file.IndexKey); err != nil {
+		return nil, fmt.Errorf("index_key: %w", err)
+	}
+
+	return p, nil
+}
+
+func (p *FileKeyProvider) CurrentKeyID() string {
+	return p.current
+}
+
+func (p *FileKeyProvider) Key(id string) ([]byte, error) {
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+func (p *FileKeyProvider) KeyIDs() []string {
+	ids := make([]string, 0, len(p.keys))
+	for id := range p.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (p *FileKeyProvider) IndexKey() []byte {
+	return p.indexKey
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, errors.New("key must be 32 bytes")
+	}
+	return key, nil
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/torrentxok/order_service/internal/models"
+	"go.uber.org/zap"
+)
+
+type AnalyticsRepository interface {
+	RevenueByCurrency(ctx context.Context, f models.StatsFilter) ([]models.RevenuePoint, error)
+	BasketSize(ctx context.Context, f models.StatsFilter) ([]models.BasketPoint, error)
+	TopBrands(ctx context.Context, f models.StatsFilter) ([]models.BrandStat, error)
+	OrdersByDeliveryService(ctx context.Context, f models.StatsFilter) ([]models.BreakdownStat, error)
+	PaymentBreakdown(ctx context.Context, f models.StatsFilter, by string) ([]models.BreakdownStat, error)
+}
+
+// колонки payment, по которым разрешена разбивка
+var paymentBreakdownColumns = map[string]string{
+	"provider": "p.provider",
+	"bank":     "p.bank",
+	"currency": "p.currency",
+}
+
+// AnalyticsRepo читает с реплик: небольшое отставание для агрегатов не важно
+type AnalyticsRepo struct {
+	repo   *OrderRepo
+	logger *zap.Logger
+}
+
+func NewAnalyticsRepository(repo *OrderRepo, logger *zap.Logger) *AnalyticsRepo {
+	return &AnalyticsRepo{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (r *AnalyticsRepo) RevenueByCurrency(ctx context.Context, f models.StatsFilter) ([]models.RevenuePoint, error) {
+	query := `
+		SELECT	date_trunc($3, o.date_created AT TIME ZONE 'UTC') AS bucket,
+				p.currency,
+				SUM(p.amount) AS revenue,
+				COUNT(*) AS orders
+		FROM orders o
+		JOIN payment p ON p.order_uid = o.order_uid AND p.date_created = o.date_created
+		WHERE o.date_created >= $1 AND o.date_created < $2
+		GROUP BY bucket, p.currency
+		ORDER BY bucket, p.currency
+	`
+
+	points := []models.RevenuePoint{}
+	done := r.repo.observe("RevenueByCurrency", query, f.From, f.To, f.GroupBy)
+	err := r.repo.reader().SelectContext(ctx, &points, query, f.From, f.To, f.GroupBy)
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to fetch revenue stats", zap.Error(err))
+		return nil, mapError(err)
+	}
+
+	return points, nil
+}
+
+func (r *AnalyticsRepo) BasketSize(ctx context.Context, f models.StatsFilter) ([]models.BasketPoint, error) {
+	query := `
+		SELECT	date_trunc($3, o.date_created AT TIME ZONE 'UTC') AS bucket,
+				COUNT(*) AS orders,
+				AVG(i.cnt)::float8 AS avg_items,
+				AVG(p.goods_total)::float8 AS avg_goods_total
+		FROM orders o
+		JOIN payment p ON p.order_uid = o.order_uid AND p.date_created = o.date_created
+		JOIN (
+			SELECT order_uid, date_created, COUNT(*) AS cnt
+			FROM items
+			WHERE date_created >= $1 AND date_created < $2
+			GROUP BY order_uid, date_created
+		) i ON i.order_uid = o.order_uid AND i.date_created = o.date_created
+		WHERE o.date_created >= $1 AND o.date_created < $2
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+
+	points := []models.BasketPoint{}
+	done := r.repo.observe("BasketSize", query, f.From, f.To, f.GroupBy)
+	err := r.repo.reader().SelectContext(ctx, &points, query, f.From, f.To, f.GroupBy)
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to fetch basket stats", zap.Error(err))
+		return nil, mapError(err)
+	}
+
+	return points, nil
+}
+
+// TopBrands суммирует total_price без учёта валюты заказа
+func (r *AnalyticsRepo) TopBrands(ctx context.Context, f models.StatsFilter) ([]models.BrandStat, error) {
+	query := `
+		SELECT	brand,
+				SUM(total_price) AS revenue,
+				COUNT(*) AS items
+		FROM items
+		WHERE date_created >= $1 AND date_created < $2
+		GROUP BY brand
+		ORDER BY revenue DESC, brand
+		LIMIT $3
+	`
+
+	stats := []models.BrandStat{}
+	done := r.repo.observe("TopBrands", query, f.From, f.To, f.Limit)
+	err := r.repo.reader().SelectContext(ctx, &stats, query, f.From, f.To, f.Limit)
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to fetch brand stats", zap.Error(err))
+		return nil, mapError(err)
+	}
+
+	return stats, nil
+}
+
+func (r *AnalyticsRepo) OrdersByDeliveryService(ctx context.Context, f models.StatsFilter) ([]models.BreakdownStat, error) {
+	query := `
+		SELECT	o.delivery_service AS key,
+				COUNT(*) AS orders,
+				COALESCE(SUM(p.amount), 0) AS amount
+		FROM orders o
+		LEFT JOIN payment p ON p.order_uid = o.order_uid AND p.date_created = o.date_created
+		WHERE o.date_created >= $1 AND o.date_created < $2
+		GROUP BY o.delivery_service
+		ORDER BY orders DESC, key
+	`
+
+	stats := []models.BreakdownStat{}
+	done := r.repo.observe("OrdersByDeliveryService", query, f.From, f.To)
+	err := r.repo.reader().SelectContext(ctx, &stats, query, f.From, f.To)
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to fetch delivery service stats", zap.Error(err))
+		return nil, mapError(err)
+	}
+
+	return stats, nil
+}
+
+func (r *AnalyticsRepo) PaymentBreakdown(ctx context.Context, f models.StatsFilter, by string) ([]models.BreakdownStat, error) {
+	column, ok := paymentBreakdownColumns[by]
+	if !ok {
+		return nil, fmt.Errorf("unsupported payment breakdown %q", by)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT	%s AS key,
+				COUNT(*) AS orders,
+				SUM(p.amount) AS amount
+		FROM payment p
+		WHERE p.date_created >= $1 AND p.date_created < $2
+		GROUP BY key
+		ORDER BY orders DESC, key
+	`, column)
+
+	stats := []models.BreakdownStat{}
+	done := r.repo.observe("PaymentBreakdown", query, f.From, f.To)
+	err := r.repo.reader().SelectContext(ctx, &stats, query, f.From, f.To)
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to fetch payment stats", zap.String("by", by), zap.Error(err))
+		return nil, mapError(err)
+	}
+
+	return stats, nil
+}
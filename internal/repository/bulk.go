@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/torrentxok/order_service/internal/models"
+	"go.uber.org/zap"
+)
+
+// OrderBulkRepository - пакетное чтение и запись для export/import и фоновых проверок
+type OrderBulkRepository interface {
+	// ScanOrders отдаёт заказы пачками по batchSize в порядке date_created
+	ScanOrders(ctx context.Context, f models.OrderFilter, batchSize int, fn func([]*models.Order) error) error
+	// CreateOrders пишет пачку в одной транзакции, уже существующие заказы пропускает
+	CreateOrders(ctx context.Context, orders []*models.Order) (int, error)
+}
+
+func (r *OrderRepo) ScanOrders(ctx context.Context, f models.OrderFilter, batchSize int, fn func([]*models.Order) error) error {
+	db := r.reader()
+
+	var (
+		lastDate time.Time
+		lastUID  string
+		first    = true
+	)
+
+	for {
+		args := []any{}
+		arg := func(v any) string {
+			args = append(args, v)
+			return fmt.Sprintf("$%d", len(args))
+		}
+
+		conds := []string{"true"}
+		if !f.From.IsZero() {
+			conds = append(conds, "date_created >= "+arg(f.From))
+		}
+		if !f.To.IsZero() {
+			conds = append(conds, "date_created < "+arg(f.To))
+		}
+		if f.CustomerID != "" {
+			conds = append(conds, "customer_id = "+arg(f.CustomerID))
+		}
+		if !first {
+			conds = append(conds, fmt.Sprintf("(date_created, order_uid) > (%s, %s)", arg(lastDate), arg(lastUID)))
+		}
+
+		query := fmt.Sprintf(`
+			SELECT order_uid, date_created
+			FROM orders
+			WHERE %s
+			ORDER BY date_created, order_uid
+			LIMIT %s
+		`, strings.Join(conds, " AND "), arg(batchSize))
+
+		var keys []struct {
+			OrderUID    string    `db:"order_uid"`
+			DateCreated time.Time `db:"date_created"`
+		}
+		done := r.observe("ScanOrders", query, args...)
+		err := db.SelectContext(ctx, &keys, query, args...)
+		done(err)
+		if err != nil {
+			r.logger.Error("failed to scan orders", zap.Error(err))
+			return mapError(err)
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+
+		uids := make([]string, len(keys))
+		for i, k := range keys {
+			uids[i] = k.OrderUID
+		}
+
+		orders, err := r.getOrders(ctx, db, uids)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(orders); err != nil {
+			return err
+		}
+
+		if len(keys) < batchSize {
+			return nil
+		}
+
+		first = false
+		lastDate, lastUID = keys[len(keys)-1].DateCreated, keys[len(keys)-1].OrderUID
+	}
+}
+
+// getOrders загружает заказы целиком четырьмя запросами на всю пачку, порядок uids сохраняется
+func (r *OrderRepo) getOrders(ctx context.Context, db *sqlx.DB, uids []string) ([]*models.Order, error) {
+	queryOrders := `
+		SELECT	order_uid, track_number, entry, locale,
+				internal_signature, customer_id, delivery_service,
+				shardkey, sm_id, date_created, oof_shard
+		FROM orders
+		WHERE order_uid = ANY($1)
+	`
+
+	var rows []*models.Order
+	done := r.observe("getOrders", queryOrders, uids)
+	err := db.SelectContext(ctx, &rows, queryOrders, pq.Array(uids))
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to fetch orders batch", zap.Error(err))
+		return nil, mapError(err)
+	}
+
+	byUID := make(map[string]*models.Order, len(rows))
+	for _, o := range rows {
+		byUID[o.OrderUID] = o
+	}
+
+	queryDelivery := `
+		SELECT	order_uid, name, phone, zip, city, address, region, email,
+				key_id, wrapped_dek
+		FROM delivery
+		WHERE order_uid = ANY($1)
+	`
+
+	var deliveries []deliveryRow
+	done = r.observe("getDeliveries", queryDelivery, uids)
+	err = db.SelectContext(ctx, &deliveries, queryDelivery, pq.Array(uids))
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to fetch deliveries batch", zap.Error(err))
+		return nil, mapError(err)
+	}
+	for i := range deliveries {
+		o, ok := byUID[deliveries[i].OrderUID]
+		if !ok {
+			continue
+		}
+
+		d, err := r.openDelivery(&deliveries[i])
+		if err != nil {
+			r.logger.Error("failed to decrypt delivery", zap.String("order_uid", o.OrderUID), zap.Error(err))
+			return nil, err
+		}
+		o.Delivery = d
+	}
+
+	queryPayment := `
+		SELECT	order_uid, transaction, request_id, currency, provider, amount,
+				payment_dt, bank, delivery_cost, goods_total, custom_fee
+		FROM payment
+		WHERE order_uid = ANY($1)
+	`
+
+	var payments []struct {
+		OrderUID string `db:"order_uid"`
+		models.Payment
+	}
+	done = r.observe("getPayments", queryPayment, uids)
+	err = db.SelectContext(ctx, &payments, queryPayment, pq.Array(uids))
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to fetch payments batch", zap.Error(err))
+		return nil, mapError(err)
+	}
+	for _, p := range payments {
+		if o, ok := byUID[p.OrderUID]; ok {
+			o.Payment = p.Payment
+		}
+	}
+
+	queryItems := `
+		SELECT	order_uid, chrt_id, track_number, price,
+				rid, name, sale, size, total_price,
+				nm_id, brand, status
+		FROM items
+		WHERE order_uid = ANY($1)
+		ORDER BY id
+	`
+
+	var items []struct {
+		OrderUID string `db:"order_uid"`
+		models.Item
+	}
+	done = r.observe("getItems", queryItems, uids)
+	err = db.SelectContext(ctx, &items, queryItems, pq.Array(uids))
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to fetch items batch", zap.Error(err))
+		return nil, mapError(err)
+	}
+	for _, it := range items {
+		if o, ok := byUID[it.OrderUID]; ok {
+			o.Items = append(o.Items, it.Item)
+		}
+	}
+
+	orders := make([]*models.Order, 0, len(uids))
+	for _, uid := range uids {
+		if o, ok := byUID[uid]; ok {
+			orders = append(orders, o)
+		}
+	}
+
+	return orders, nil
+}
+
+func (r *OrderRepo) CreateOrders(ctx context.Context, orders []*models.Order) (int, error) {
+	if len(orders) == 0 {
+		return 0, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return 0, mapError(err)
+	}
+
+	uids := make([]string, len(orders))
+	for i, o := range orders {
+		uids[i] = o.OrderUID
+	}
+
+	query := `SELECT order_uid FROM order_uids WHERE order_uid = ANY($1)`
+	done := r.observe("CreateOrders", query, uids)
+	rows, err := tx.QueryContext(ctx, query, pq.Array(uids))
+	done(err)
+	if err != nil {
+		tx.Rollback()
+		return 0, mapError(err)
+	}
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return 0, mapError(err)
+		}
+		existing[uid] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return 0, mapError(err)
+	}
+
+	created := 0
+	for _, o := range orders {
+		// дубликаты внутри пачки тоже пропускаются
+		if existing[o.OrderUID] {
+			continue
+		}
+		existing[o.OrderUID] = true
+
+		err := r.insertOrder(ctx, tx, o)
+		if err == nil {
+			err = r.insertDelivery(ctx, tx, o.OrderUID, o.DateCreated, &o.Delivery)
+		}
+		if err == nil {
+			err = r.insertPayment(ctx, tx, o.OrderUID, o.DateCreated, &o.Payment)
+		}
+		if err == nil {
+			err = r.insertItems(ctx, tx, o.OrderUID, o.DateCreated, o.Items)
+		}
+		if err == nil {
+			err = r.notifyChange(ctx, tx, OpCreate, o.OrderUID)
+		}
+		if err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("order %s: %w", o.OrderUID, mapError(err))
+		}
+
+		created++
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return 0, mapError(err)
+	}
+
+	return created, nil
+}
+
+// existingOrders - какие из uids уже заняты в этой БД
+func (r *OrderRepo) existingOrders(ctx context.Context, uids []string) ([]string, error) {
+	query := `SELECT order_uid FROM order_uids WHERE order_uid = ANY($1)`
+
+	var existing []string
+	done := r.observe("existingOrders", query, uids)
+	err := r.db.SelectContext(ctx, &existing, query, pq.Array(uids))
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to check existing orders", zap.Int("count", len(uids)), zap.Error(err))
+		return nil, mapError(err)
+	}
+
+	return existing, nil
+}
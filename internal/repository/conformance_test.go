@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/config"
+	"github.com/torrentxok/order_service/internal/models"
+	"go.uber.org/zap"
+)
+
+// TEST_DATABASE_URL - база со схемой из SQL/model.sql; без неё Postgres пропускается
+const testDatabaseURLEnv = "TEST_DATABASE_URL"
+
+func TestMemoryRepoConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) OrderRepository {
+		return NewMemoryRepository()
+	})
+}
+
+func TestOrderRepoConformance(t *testing.T) {
+	url := os.Getenv(testDatabaseURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", testDatabaseURLEnv)
+	}
+
+	runConformance(t, func(t *testing.T) OrderRepository {
+		cfg := config.DBConfig{
+			URL:            url,
+			MaxOpenConns:   4,
+			MaxIdleConns:   4,
+			PingTimeout:    5 * time.Second,
+			ConnectBackoff: time.Second,
+		}
+
+		repo, err := NewRepository(context.Background(), cfg, zap.NewNop())
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+
+		return repo
+	})
+}
+
+// runConformance - поведение, общее для всех реализаций OrderRepository
+func runConformance(t *testing.T, newRepo func(t *testing.T) OrderRepository) {
+	// uid уникальны на запуск, чтобы не пересекаться с данными в общей базе
+	prefix := fmt.Sprintf("conformance-%d-", time.Now().UnixNano())
+
+	// заказы создаются через create, чтобы убрать их за собой
+	setup := func(t *testing.T) (OrderRepository, func(o *models.Order) error) {
+		repo := newRepo(t)
+		ctx := context.Background()
+
+		create := func(o *models.Order) error {
+			err := repo.CreateOrder(ctx, o)
+			if err == nil {
+				t.Cleanup(func() { repo.DeleteOrder(context.Background(), o.OrderUID) })
+			}
+			return err
+		}
+		return repo, create
+	}
+
+	t.Run("GetMissing", func(t *testing.T) {
+		repo, _ := setup(t)
+
+		_, err := repo.GetOrder(context.Background(), prefix+"missing")
+		if !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("GetOrder error = %v, want ErrOrderNotFound", err)
+		}
+	})
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repo, create := setup(t)
+		ctx := context.Background()
+
+		want := conformanceOrder(prefix+"roundtrip", time.Date(2100, 1, 10, 12, 0, 0, 0, time.UTC))
+		if err := create(want); err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+
+		got, err := repo.GetOrder(ctx, want.OrderUID)
+		if err != nil {
+			t.Fatalf("GetOrder: %v", err)
+		}
+		assertSameOrder(t, got, want)
+
+		ok, err := repo.Exists(ctx, want.OrderUID)
+		if err != nil || !ok {
+			t.Fatalf("Exists = %v, %v; want true, nil", ok, err)
+		}
+
+		ok, err = repo.Exists(ctx, prefix+"missing")
+		if err != nil || ok {
+			t.Fatalf("Exists(missing) = %v, %v; want false, nil", ok, err)
+		}
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		_, create := setup(t)
+
+		created := time.Date(2100, 2, 10, 12, 0, 0, 0, time.UTC)
+		if err := create(conformanceOrder(prefix+"dup", created)); err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+
+		err := create(conformanceOrder(prefix+"dup", created))
+		if !errors.Is(err, ErrOrderExists) {
+			t.Fatalf("same date: error = %v, want ErrOrderExists", err)
+		}
+
+		// другая дата - другая партиция, uid всё равно должен быть уникален
+		err = create(conformanceOrder(prefix+"dup", created.AddDate(0, 3, 0)))
+		if !errors.Is(err, ErrOrderExists) {
+			t.Fatalf("other date: error = %v, want ErrOrderExists", err)
+		}
+	})
+
+	t.Run("GetLastOrders", func(t *testing.T) {
+		repo, create := setup(t)
+
+		// в далёком будущем, чтобы в общей базе они были последними
+		base := time.Date(2101, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, uid := range []string{"last-b", "last-c", "last-a"} {
+			created := base.Add(time.Duration([]int{2, 3, 1}[i]) * time.Hour)
+			if err := create(conformanceOrder(prefix+uid, created)); err != nil {
+				t.Fatalf("CreateOrder: %v", err)
+			}
+		}
+
+		orders, err := repo.GetLastOrders(context.Background(), 2)
+		if err != nil {
+			t.Fatalf("GetLastOrders: %v", err)
+		}
+		if len(orders) != 2 {
+			t.Fatalf("got %d orders, want 2", len(orders))
+		}
+		if orders[0].OrderUID != prefix+"last-c" || orders[1].OrderUID != prefix+"last-b" {
+			t.Fatalf("order = %s, %s; want newest first", orders[0].OrderUID, orders[1].OrderUID)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo, create := setup(t)
+		ctx := context.Background()
+
+		o := conformanceOrder(prefix+"delete", time.Date(2100, 3, 10, 12, 0, 0, 0, time.UTC))
+		if err := create(o); err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+
+		if err := repo.DeleteOrder(ctx, o.OrderUID); err != nil {
+			t.Fatalf("DeleteOrder: %v", err)
+		}
+		if _, err := repo.GetOrder(ctx, o.OrderUID); !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("GetOrder after delete: error = %v, want ErrOrderNotFound", err)
+		}
+		if err := repo.DeleteOrder(ctx, o.OrderUID); !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("second DeleteOrder: error = %v, want ErrOrderNotFound", err)
+		}
+
+		// после удаления uid снова свободен
+		if err := create(o); err != nil {
+			t.Fatalf("CreateOrder after delete: %v", err)
+		}
+	})
+
+	t.Run("ReturnsCopy", func(t *testing.T) {
+		repo, create := setup(t)
+		ctx := context.Background()
+
+		o := conformanceOrder(prefix+"copy", time.Date(2100, 4, 10, 12, 0, 0, 0, time.UTC))
+		if err := create(o); err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+
+		got, err := repo.GetOrder(ctx, o.OrderUID)
+		if err != nil {
+			t.Fatalf("GetOrder: %v", err)
+		}
+		got.TrackNumber = "changed"
+		got.Items[0].Name = "changed"
+
+		again, err := repo.GetOrder(ctx, o.OrderUID)
+		if err != nil {
+			t.Fatalf("GetOrder: %v", err)
+		}
+		assertSameOrder(t, again, o)
+	})
+
+	t.Run("CanceledContext", func(t *testing.T) {
+		repo, _ := setup(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if _, err := repo.GetOrder(ctx, prefix+"missing"); err == nil || errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("GetOrder with canceled ctx: error = %v, want context error", err)
+		}
+	})
+}
+
+func conformanceOrder(uid string, created time.Time) *models.Order {
+	return &models.Order{
+		OrderUID:    uid,
+		TrackNumber: "WBILMTESTTRACK",
+		Entry:       "WBIL",
+		Delivery: models.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: models.Payment{
+			Transaction:  uid,
+			Currency:     "USD",
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDT:    1637907727,
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []models.Item{{
+			ChrtID:      9934930,
+			TrackNumber: "WBILMTESTTRACK",
+			Price:       453,
+			Rid:         "ab4219087a764ae0btest",
+			Name:        "Mascaras",
+			Sale:        30,
+			Size:        "0",
+			TotalPrice:  317,
+			NmID:        2389212,
+			Brand:       "Vivienne Sabo",
+			Status:      202,
+		}},
+		Locale:          "en",
+		CustomerID:      "test",
+		DeliveryService: "meest",
+		ShardKey:        "9",
+		SmID:            99,
+		DateCreated:     created.Format(time.RFC3339),
+		OofShard:        "1",
+	}
+}
+
+func assertSameOrder(t *testing.T, got, want *models.Order) {
+	t.Helper()
+
+	if got.OrderUID != want.OrderUID || got.TrackNumber != want.TrackNumber || got.CustomerID != want.CustomerID {
+		t.Fatalf("order = %+v, want %+v", got, want)
+	}
+
+	// Postgres может вернуть дату в другом часовом поясе
+	gotCreated, err := time.Parse(time.RFC3339, got.DateCreated)
+	if err != nil {
+		t.Fatalf("date_created %q: %v", got.DateCreated, err)
+	}
+	if !gotCreated.Equal(parseDateCreated(want)) {
+		t.Fatalf("date_created = %s, want %s", got.DateCreated, want.DateCreated)
+	}
+
+	if got.Delivery != want.Delivery {
+		t.Fatalf("delivery = %+v, want %+v", got.Delivery, want.Delivery)
+	}
+	if got.Payment != want.Payment {
+		t.Fatalf("payment = %+v, want %+v", got.Payment, want.Payment)
+	}
+	if len(got.Items) != len(want.Items) || got.Items[0] != want.Items[0] {
+		t.Fatalf("items = %+v, want %+v", got.Items, want.Items)
+	}
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/torrentxok/order_service/internal/config"
+	"go.uber.org/zap"
+)
+
+const maxConnectBackoff = 30 * time.Second
+
+func buildDSN(cfg config.DBConfig) string {
+	if cfg.URL != "" {
+		return cfg.URL
+	}
+
+	params := []string{
+		"host=" + dsnValue(cfg.Host),
+		fmt.Sprintf("port=%d", cfg.Port),
+		"user=" + dsnValue(cfg.User),
+		"password=" + dsnValue(cfg.Password),
+		"dbname=" + dsnValue(cfg.Name),
+		"sslmode=" + dsnValue(cfg.SSLMode),
+	}
+	if cfg.SSLRootCert != "" {
+		params = append(params, "sslrootcert="+dsnValue(cfg.SSLRootCert))
+	}
+	if cfg.ApplicationName != "" {
+		params = append(params, "application_name="+dsnValue(cfg.ApplicationName))
+	}
+	// неизвестные драйверу параметры pq передаёт серверу как runtime-настройки
+	if cfg.StatementTimeout > 0 {
+		params = append(params, fmt.Sprintf("statement_timeout=%d", cfg.StatementTimeout.Milliseconds()))
+	}
+
+	return strings.Join(params, " ")
+}
+
+// dsnValue экранирует значение для key=value формата libpq
+func dsnValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+// openDB открывает пул без проверки соединения
+func openDB(cfg config.DBConfig) (*sqlx.DB, error) {
+	db, err := sqlx.Open("postgres", buildDSN(cfg))
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	return db, nil
+}
+
+// connect открывает пул и ждёт, пока БД начнёт отвечать; отмена ctx прерывает ожидание
+func connect(ctx context.Context, cfg config.DBConfig, logger *zap.Logger) (*sqlx.DB, error) {
+	db, err := openDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	backoff := cfg.ConnectBackoff
+	for attempt := 0; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, cfg.PingTimeout)
+		err = db.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			return db, nil
+		}
+
+		if ctx.Err() != nil {
+			db.Close()
+			return nil, fmt.Errorf("db connect interrupted: %w", ctx.Err())
+		}
+
+		if attempt >= cfg.ConnectRetries {
+			db.Close()
+			return nil, fmt.Errorf("db ping failed after %d attempts: %w", attempt+1, err)
+		}
+
+		logger.Warn("database is not ready, retrying",
+			zap.Int("attempt", attempt+1),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, fmt.Errorf("db connect interrupted: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
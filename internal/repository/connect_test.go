@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestConnectStopsOnCancel(t *testing.T) {
+	// порт 1 никто не слушает: каждая попытка быстро падает, дальше ожидание backoff
+	cfg := config.DBConfig{
+		URL:            "postgres://app@127.0.0.1:1/orders?sslmode=disable",
+		PingTimeout:    time.Second,
+		ConnectRetries: 10,
+		ConnectBackoff: time.Minute,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := connect(ctx, cfg, zap.NewNop())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("connect error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("connect returned after %s, want it to stop on cancel", elapsed)
+	}
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/models"
+	"go.uber.org/zap"
+)
+
+// ConsistencyChecker ищет заказы с недостающими или противоречивыми строками
+type ConsistencyChecker interface {
+	// CheckConsistency проходит все заказы пачками по batchSize;
+	// fn получает найденные в пачке нарушения, пустые пачки не передаются
+	CheckConsistency(ctx context.Context, batchSize int, fn func([]models.Inconsistency) error) error
+	DeleteOrder(ctx context.Context, orderUID string) error
+}
+
+func (r *OrderRepo) CheckConsistency(ctx context.Context, batchSize int, fn func([]models.Inconsistency) error) error {
+	query := `
+		WITH batch AS (
+			SELECT order_uid, date_created, track_number
+			FROM orders
+			WHERE (date_created, order_uid) > ($1, $2)
+			ORDER BY date_created, order_uid
+			LIMIT $3
+		)
+		SELECT
+			b.order_uid, b.date_created,
+			d.order_uid IS NOT NULL AS has_delivery,
+			p.order_uid IS NOT NULL AS has_payment,
+			p.goods_total,
+			i.item_count, i.items_total, i.track_mismatch
+		FROM batch b
+		LEFT JOIN delivery d ON d.order_uid = b.order_uid AND d.date_created = b.date_created
+		LEFT JOIN payment p ON p.order_uid = b.order_uid AND p.date_created = b.date_created
+		CROSS JOIN LATERAL (
+			SELECT
+				count(*) AS item_count,
+				COALESCE(sum(total_price), 0) AS items_total,
+				count(*) FILTER (WHERE track_number <> b.track_number) AS track_mismatch
+			FROM items
+			WHERE order_uid = b.order_uid AND date_created = b.date_created
+		) i
+		ORDER BY b.date_created, b.order_uid
+	`
+
+	var (
+		lastDate time.Time
+		lastUID  string
+	)
+
+	for {
+		var rows []struct {
+			OrderUID      string        `db:"order_uid"`
+			DateCreated   time.Time     `db:"date_created"`
+			HasDelivery   bool          `db:"has_delivery"`
+			HasPayment    bool          `db:"has_payment"`
+			GoodsTotal    sql.NullInt64 `db:"goods_total"`
+			ItemCount     int           `db:"item_count"`
+			ItemsTotal    int64         `db:"items_total"`
+			TrackMismatch int           `db:"track_mismatch"`
+		}
+
+		done := r.observe("CheckConsistency", query, lastDate, lastUID, batchSize)
+		err := r.reader().SelectContext(ctx, &rows, query, lastDate, lastUID, batchSize)
+		done(err)
+		if err != nil {
+			r.logger.Error("failed to check consistency", zap.Error(err))
+			return mapError(err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		var issues []models.Inconsistency
+		for _, row := range rows {
+			issue := func(kind, detail string) {
+				issues = append(issues, models.Inconsistency{
+					OrderUID:    row.OrderUID,
+					DateCreated: row.DateCreated,
+					Kind:        kind,
+					Detail:      detail,
+				})
+			}
+
+			if !row.HasDelivery {
+				issue(models.IssueMissingDelivery, "")
+			}
+			if !row.HasPayment {
+				issue(models.IssueMissingPayment, "")
+			}
+			if row.ItemCount == 0 {
+				issue(models.IssueNoItems, "")
+			}
+			if row.TrackMismatch > 0 {
+				issue(models.IssueTrackMismatch, fmt.Sprintf("%d of %d items", row.TrackMismatch, row.ItemCount))
+			}
+			if row.GoodsTotal.Valid && row.ItemCount > 0 && row.GoodsTotal.Int64 != row.ItemsTotal {
+				issue(models.IssueGoodsTotalMismatch, fmt.Sprintf("goods_total %d, items sum %d", row.GoodsTotal.Int64, row.ItemsTotal))
+			}
+		}
+
+		if len(issues) > 0 {
+			if err := fn(issues); err != nil {
+				return err
+			}
+		}
+
+		if len(rows) < batchSize {
+			return nil
+		}
+
+		last := rows[len(rows)-1]
+		lastDate, lastUID = last.DateCreated, last.OrderUID
+	}
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+	"github.com/torrentxok/order_service/internal/models"
+	"go.uber.org/zap"
+)
+
+type CustomerDataRepository interface {
+	// PseudonymizeCustomer заменяет ПДн доставки во всех заказах покупателя, возвращает их order_uid
+	PseudonymizeCustomer(ctx context.Context, customerID string, replacement models.Delivery) ([]string, error)
+}
+
+type DataRequestRepository interface {
+	RecordDataRequest(ctx context.Context, req *models.DataRequest) error
+	DeleteRawMessagesForOrders(ctx context.Context, orderUIDs []string) (int64, error)
+}
+
+// PseudonymizeCustomer меняет только delivery: финансовые данные заказа остаются для бухгалтерии
+func (r *OrderRepo) PseudonymizeCustomer(ctx context.Context, customerID string, replacement models.Delivery) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return nil, mapError(err)
+	}
+
+	query := `
+		UPDATE delivery d
+		SET name = $2, phone = $3, email = $4, address = $5,
+			key_id = NULL, wrapped_dek = NULL, phone_bidx = NULL, email_bidx = NULL
+		FROM orders o
+		WHERE o.order_uid = d.order_uid
+			AND o.date_created = d.date_created
+			AND o.customer_id = $1
+		RETURNING d.order_uid
+	`
+
+	args := []any{
+		customerID,
+		replacement.Name,
+		replacement.Phone,
+		replacement.Email,
+		replacement.Address,
+	}
+
+	done := r.observe("PseudonymizeCustomer", query, args...)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	done(err)
+	if err != nil {
+		tx.Rollback()
+		r.logger.Error("failed to pseudonymize delivery", zap.String("customer_id", customerID), zap.Error(err))
+		return nil, mapError(err)
+	}
+
+	var uids []string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, mapError(err)
+		}
+		uids = append(uids, uid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, mapError(err)
+	}
+
+	for _, uid := range uids {
+		if err := r.notifyChange(ctx, tx, OpUpdate, uid); err != nil {
+			tx.Rollback()
+			return nil, mapError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return nil, mapError(err)
+	}
+
+	return uids, nil
+}
+
+func (r *OrderRepo) RecordDataRequest(ctx context.Context, req *models.DataRequest) error {
+	query := `
+		INSERT INTO data_requests (customer_id, request_type, requested_by, orders_affected)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	args := []any{
+		req.CustomerID,
+		req.Type,
+		req.RequestedBy,
+		req.OrdersAffected,
+	}
+
+	done := r.observe("RecordDataRequest", query, args...)
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&req.ID, &req.CreatedAt)
+	done(err)
+
+	if err != nil {
+		r.logger.Error("failed to record data request", zap.String("customer_id", req.CustomerID), zap.Error(err))
+		return mapError(err)
+	}
+	return nil
+}
+
+// DeleteRawMessagesForOrders удаляет исходные сообщения: в них те же ПДн
+func (r *OrderRepo) DeleteRawMessagesForOrders(ctx context.Context, orderUIDs []string) (int64, error) {
+	query := `DELETE FROM raw_messages WHERE order_uid = ANY($1)`
+	done := r.observe("DeleteRawMessagesForOrders", query, orderUIDs)
+	res, err := r.db.ExecContext(ctx, query, pq.Array(orderUIDs))
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to delete raw messages", zap.Error(err))
+		return 0, mapError(err)
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,309 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/pii"
+	"go.uber.org/zap"
+)
+
+var errNoCipher = errors.New("data is encrypted but encryption is not configured")
+
+// deliveryRow - строка delivery вместе с параметрами шифрования
+type deliveryRow struct {
+	OrderUID string `db:"order_uid"`
+	models.Delivery
+	KeyID      sql.NullString `db:"key_id"`
+	WrappedDEK []byte         `db:"wrapped_dek"`
+}
+
+// sealedDelivery - то, что пишется в таблицу
+type sealedDelivery struct {
+	models.Delivery
+	KeyID      *string
+	WrappedDEK []byte
+	PhoneIndex *string
+	EmailIndex *string
+}
+
+// skippedRows - строки, пропущенные ротацией ключей. Без них испорченные строки
+// выбирались бы снова и, набравшись на целую пачку, остановили бы ротацию.
+type skippedRows struct {
+	mu         sync.Mutex
+	deliveries []string
+	raw        []int64
+}
+
+func (s *skippedRows) skipDelivery(orderUID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, orderUID)
+}
+
+func (s *skippedRows) skipRaw(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.raw = append(s.raw, id)
+}
+
+func (s *skippedRows) snapshot() ([]string, []int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.deliveries...), append([]int64{}, s.raw...)
+}
+
+// EnableEncryption включает шифрование ПДн доставки для новых записей
+// и расшифровку при чтении
+func (r *OrderRepo) EnableEncryption(c *pii.Cipher) {
+	r.cipher = c
+}
+
+func (r *OrderRepo) sealDelivery(d *models.Delivery) (sealedDelivery, error) {
+	out := sealedDelivery{Delivery: *d}
+	if r.cipher == nil {
+		return out, nil
+	}
+
+	sealed, err := r.cipher.Seal(d.Name, d.Phone, d.Email, d.Address)
+	if err != nil {
+		return out, err
+	}
+
+	phoneIdx := r.cipher.BlindIndex("phone", d.Phone)
+	emailIdx := r.cipher.BlindIndex("email", d.Email)
+
+	out.Name, out.Phone, out.Email, out.Address = sealed.Fields[0], sealed.Fields[1], sealed.Fields[2], sealed.Fields[3]
+	out.KeyID = &sealed.KeyID
+	out.WrappedDEK = sealed.WrappedDEK
+	out.PhoneIndex = &phoneIdx
+	out.EmailIndex = &emailIdx
+
+	return out, nil
+}
+
+func (r *OrderRepo) openDelivery(row *deliveryRow) (models.Delivery, error) {
+	d := row.Delivery
+	if !row.KeyID.Valid {
+		return d, nil
+	}
+	if r.cipher == nil {
+		return d, errNoCipher
+	}
+
+	plain, err := r.cipher.Open(row.KeyID.String, row.WrappedDEK, d.Name, d.Phone, d.Email, d.Address)
+	if err != nil {
+		return d, err
+	}
+
+	d.Name, d.Phone, d.Email, d.Address = plain[0], plain[1], plain[2], plain[3]
+	return d, nil
+}
+
+// ReencryptDeliveries переводит пачку строк на текущий ключ: открытые строки шифрует,
+// для зашифрованных старым ключом перешифровывает только DEK. Возвращает число
+// обновлённых строк. Строки под неизвестными ключами не выбираются, а строки,
+// которые не удалось перешифровать, пропускаются с ошибкой в логе и больше
+// не выбираются до перезапуска.
+func (r *OrderRepo) ReencryptDeliveries(ctx context.Context, batchSize int) (int, error) {
+	if r.cipher == nil {
+		return 0, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, mapError(err)
+	}
+	defer tx.Rollback()
+
+	// IS NULL и = ANY обслуживает idx_delivery_key_id, строки под текущим ключом не читаются
+	query := `
+		SELECT	order_uid, date_created, name, phone, email, address, key_id, wrapped_dek
+		FROM delivery
+		WHERE (key_id IS NULL OR key_id = ANY($1)) AND NOT order_uid = ANY($3)
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+
+	skipped, _ := r.reencryptSkipped.snapshot()
+	retired := pq.Array(r.cipher.RetiredKeyIDs())
+	done := r.observe("ReencryptDeliveries", query, retired, batchSize, pq.Array(skipped))
+	rows, err := tx.QueryContext(ctx, query, retired, batchSize, pq.Array(skipped))
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to select deliveries for reencryption", zap.Error(err))
+		return 0, mapError(err)
+	}
+
+	type pending struct {
+		orderUID    string
+		dateCreated time.Time
+		row         deliveryRow
+	}
+
+	var batch []pending
+	for rows.Next() {
+		var p pending
+		err := rows.Scan(&p.orderUID, &p.dateCreated,
+			&p.row.Name, &p.row.Phone, &p.row.Email, &p.row.Address,
+			&p.row.KeyID, &p.row.WrappedDEK,
+		)
+		if err != nil {
+			rows.Close()
+			return 0, mapError(err)
+		}
+		batch = append(batch, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, mapError(err)
+	}
+
+	updated := 0
+	for _, p := range batch {
+		if p.row.KeyID.Valid {
+			keyID, wrapped, err := r.cipher.Rewrap(p.row.KeyID.String, p.row.WrappedDEK)
+			if err != nil {
+				// испорченная строка не должна останавливать ротацию остальных
+				r.logger.Error("failed to rewrap delivery key, row skipped",
+					zap.String("order_uid", p.orderUID),
+					zap.String("key_id", p.row.KeyID.String),
+					zap.Error(err),
+				)
+				r.reencryptSkipped.skipDelivery(p.orderUID)
+				continue
+			}
+
+			query := `UPDATE delivery SET key_id = $3, wrapped_dek = $4 WHERE order_uid = $1 AND date_created = $2`
+			args := []any{p.orderUID, p.dateCreated, keyID, wrapped}
+
+			done := r.observe("rewrapDelivery", query, args...)
+			_, err = tx.ExecContext(ctx, query, args...)
+			done(err)
+			if err != nil {
+				return 0, mapError(err)
+			}
+			updated++
+			continue
+		}
+
+		sealed, err := r.sealDelivery(&p.row.Delivery)
+		if err != nil {
+			r.logger.Error("failed to encrypt delivery, row skipped", zap.String("order_uid", p.orderUID), zap.Error(err))
+			r.reencryptSkipped.skipDelivery(p.orderUID)
+			continue
+		}
+
+		query := `
+			UPDATE delivery
+			SET name = $3, phone = $4, email = $5, address = $6,
+				key_id = $7, wrapped_dek = $8, phone_bidx = $9, email_bidx = $10
+			WHERE order_uid = $1 AND date_created = $2`
+		args := []any{
+			p.orderUID, p.dateCreated,
+			sealed.Name, sealed.Phone, sealed.Email, sealed.Address,
+			sealed.KeyID, sealed.WrappedDEK, sealed.PhoneIndex, sealed.EmailIndex,
+		}
+
+		done := r.observe("encryptDelivery", query, args...)
+		_, err = tx.ExecContext(ctx, query, args...)
+		done(err)
+		if err != nil {
+			return 0, mapError(err)
+		}
+		updated++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, mapError(err)
+	}
+
+	return updated, nil
+}
+
+// ReencryptRawMessages перешифровывает DEK сообщений под выведенными ключами, иначе
+// после удаления ключа их нельзя будет прочитать. Открытые сообщения не шифруются:
+// они уйдут по сроку хранения. Возвращает число обновлённых строк.
+func (r *OrderRepo) ReencryptRawMessages(ctx context.Context, batchSize int) (int, error) {
+	if r.cipher == nil {
+		return 0, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, mapError(err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, key_id, wrapped_dek
+		FROM raw_messages
+		WHERE key_id = ANY($1) AND NOT id = ANY($3)
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+
+	_, skipped := r.reencryptSkipped.snapshot()
+	retired := pq.Array(r.cipher.RetiredKeyIDs())
+	done := r.observe("ReencryptRawMessages", query, retired, batchSize, pq.Array(skipped))
+	rows, err := tx.QueryContext(ctx, query, retired, batchSize, pq.Array(skipped))
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to select raw messages for reencryption", zap.Error(err))
+		return 0, mapError(err)
+	}
+
+	type pending struct {
+		id         int64
+		keyID      string
+		wrappedDEK []byte
+	}
+
+	var batch []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.keyID, &p.wrappedDEK); err != nil {
+			rows.Close()
+			return 0, mapError(err)
+		}
+		batch = append(batch, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, mapError(err)
+	}
+
+	updated := 0
+	for _, p := range batch {
+		keyID, wrapped, err := r.cipher.Rewrap(p.keyID, p.wrappedDEK)
+		if err != nil {
+			r.logger.Error("failed to rewrap raw message key, row skipped",
+				zap.Int64("id", p.id),
+				zap.String("key_id", p.keyID),
+				zap.Error(err),
+			)
+			r.reencryptSkipped.skipRaw(p.id)
+			continue
+		}
+
+		query := `UPDATE raw_messages SET key_id = $2, wrapped_dek = $3 WHERE id = $1`
+		done := r.observe("rewrapRawMessage", query, p.id, keyID, wrapped)
+		_, err = tx.ExecContext(ctx, query, p.id, keyID, wrapped)
+		done(err)
+		if err != nil {
+			return 0, mapError(err)
+		}
+		updated++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, mapError(err)
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrOrderNotFound = errors.New("order not found")
+	ErrOrderExists   = errors.New("order already exists")
+	// заказ с одним order_uid лежит в нескольких шардах - какой из них верный, неизвестно
+	ErrShardConflict = errors.New("order found in several shards")
+
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrCheckViolation      = errors.New("check violation")
+	ErrNotNullViolation    = errors.New("not null violation")
+
+	// операцию можно повторить целиком: serialization failure, deadlock
+	ErrRetryable = errors.New("retryable database error")
+	// нет соединения с БД
+	ErrUnavailable = errors.New("database unavailable")
+	// истёк дедлайн контекста или statement_timeout
+	ErrTimeout = errors.New("database timeout")
+
+	// условие поиска нельзя выполнить при текущей схеме хранения
+	ErrUnsupportedSearch = errors.New("unsupported search condition")
+)
+
+// mapError оборачивает ошибки драйвера в типизированные ошибки пакета,
+// исходная ошибка остаётся доступна через errors.As
+func mapError(err error) error {
+	if err == nil || isMapped(err) {
+		return err
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+
+	if errors.Is(err, driver.ErrBadConn) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "23505":
+			return fmt.Errorf("%w: %w", ErrOrderExists, err)
+		case pqErr.Code == "23503":
+			return fmt.Errorf("%w: %w", ErrForeignKeyViolation, err)
+		case pqErr.Code == "23514":
+			return fmt.Errorf("%w: %w", ErrCheckViolation, err)
+		case pqErr.Code == "23502":
+			return fmt.Errorf("%w: %w", ErrNotNullViolation, err)
+		case pqErr.Code == "40001", pqErr.Code == "40P01":
+			return fmt.Errorf("%w: %w", ErrRetryable, err)
+		case pqErr.Code == "57014":
+			return fmt.Errorf("%w: %w", ErrTimeout, err)
+		case pqErr.Code.Class() == "08", pqErr.Code == "57P01", pqErr.Code == "57P02", pqErr.Code == "57P03":
+			return fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return fmt.Errorf("%w: %w", ErrTimeout, err)
+		}
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	return err
+}
+
+func isMapped(err error) bool {
+	for _, target := range []error{
+		ErrOrderNotFound, ErrOrderExists,
+		ErrForeignKeyViolation, ErrCheckViolation, ErrNotNullViolation,
+		ErrRetryable, ErrUnavailable, ErrTimeout,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
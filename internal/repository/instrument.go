@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/torrentxok/order_service/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// observe замеряет один запрос: вызов возвращённой функции пишет гистограмму
+// и, если запрос дольше порога, лог с запросом и типами параметров вместо значений
+func (r *OrderRepo) observe(op, query string, args ...any) func(error) {
+	start := time.Now()
+
+	return func(err error) {
+		elapsed := time.Since(start)
+
+		status := "ok"
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			status = "error"
+		}
+		metrics.DBQueryDuration.WithLabelValues(op, status).Observe(elapsed.Seconds())
+
+		if r.slowQuery > 0 && elapsed >= r.slowQuery {
+			r.logger.Warn("slow query",
+				zap.String("operation", op),
+				zap.Duration("duration", elapsed),
+				zap.String("query", strings.Join(strings.Fields(query), " ")),
+				zap.Strings("params", redact(args)),
+			)
+		}
+	}
+}
+
+func redact(args []any) []string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		out[i] = fmt.Sprintf("$%d=<%T>", i+1, a)
+	}
+	return out
+}
+
+// RegisterMetrics экспортирует sql.DBStats primary и реплик с меткой db_name
+func (r *OrderRepo) RegisterMetrics(reg prometheus.Registerer, name string) error {
+	if err := reg.Register(collectors.NewDBStatsCollector(r.db.DB, name)); err != nil {
+		return err
+	}
+
+	for _, rep := range r.replicas.replicas {
+		if err := reg.Register(collectors.NewDBStatsCollector(rep.db.DB, name+"/replica/"+rep.addr)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/models"
+)
+
+// MemoryRepo - in-memory реализация OrderRepository для тестов и локальной разработки.
+// Реализует и ChangeNotifier, заменяя LISTEN/NOTIFY.
+type MemoryRepo struct {
+	mu     sync.RWMutex
+	orders map[string]*models.Order
+	raw    []*models.RawMessage
+	rawID  int64
+	subs   map[chan OrderChange]struct{}
+}
+
+var (
+	_ OrderRepository      = (*MemoryRepo)(nil)
+	_ RawMessageRepository = (*MemoryRepo)(nil)
+	_ ChangeNotifier       = (*MemoryRepo)(nil)
+	_ ChangePublisher      = (*MemoryRepo)(nil)
+)
+
+func NewMemoryRepository() *MemoryRepo {
+	return &MemoryRepo{
+		orders: make(map[string]*models.Order),
+		subs:   make(map[chan OrderChange]struct{}),
+	}
+}
+
+func (r *MemoryRepo) CreateOrder(ctx context.Context, o *models.Order) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.orders[o.OrderUID]; ok {
+		return ErrOrderExists
+	}
+
+	r.orders[o.OrderUID] = cloneOrder(o)
+	r.publish(OrderChange{Op: OpCreate, OrderUID: o.OrderUID})
+	return nil
+}
+
+func (r *MemoryRepo) GetOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	order, ok := r.orders[orderUID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+
+	return cloneOrder(order), nil
+}
+
+func (r *MemoryRepo) Exists(ctx context.Context, orderUID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.orders[orderUID]
+	return ok, nil
+}
+
+func (r *MemoryRepo) GetLastOrders(ctx context.Context, limit int) ([]*models.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	orders := make([]*models.Order, 0, len(r.orders))
+	for _, o := range r.orders {
+		orders = append(orders, o)
+	}
+
+	// как ORDER BY date_created DESC
+	sort.SliceStable(orders, func(i, j int) bool {
+		return parseDateCreated(orders[i]).After(parseDateCreated(orders[j]))
+	})
+
+	if limit >= 0 && len(orders) > limit {
+		orders = orders[:limit]
+	}
+
+	result := make([]*models.Order, 0, len(orders))
+	for _, o := range orders {
+		result = append(result, cloneOrder(o))
+	}
+
+	return result, nil
+}
+
+func (r *MemoryRepo) DeleteOrder(ctx context.Context, orderUID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.orders[orderUID]; !ok {
+		return ErrOrderNotFound
+	}
+
+	delete(r.orders, orderUID)
+	r.publish(OrderChange{Op: OpDelete, OrderUID: orderUID})
+	return nil
+}
+
+func (r *MemoryRepo) Listen(ctx context.Context) (<-chan OrderChange, error) {
+	ch := make(chan OrderChange, 64)
+
+	r.mu.Lock()
+	r.subs[ch] = struct{}{}
+	r.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		r.mu.Lock()
+		delete(r.subs, ch)
+		close(ch)
+		r.mu.Unlock()
+	}()
+
+	return ch, nil
+}
+
+func (r *MemoryRepo) PublishChange(ctx context.Context, change OrderChange) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.publish(change)
+	return nil
+}
+
+// publish вызывается под r.mu; медленный подписчик теряет события, как при разрыве LISTEN
+func (r *MemoryRepo) publish(change OrderChange) {
+	for ch := range r.subs {
+		select {
+		case ch <- change:
+		default:
+		}
+	}
+}
+
+func (r *MemoryRepo) SaveRawMessage(ctx context.Context, msg *models.RawMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rawID++
+	msg.ID = r.rawID
+
+	c := *msg
+	r.raw = append(r.raw, &c)
+	return nil
+}
+
+func (r *MemoryRepo) GetRawMessages(ctx context.Context, orderUID string) ([]*models.RawMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var msgs []*models.RawMessage
+	for _, m := range r.raw {
+		if m.OrderUID != nil && *m.OrderUID == orderUID {
+			c := *m
+			msgs = append(msgs, &c)
+		}
+	}
+
+	return msgs, nil
+}
+
+func (r *MemoryRepo) DeleteRawMessagesBefore(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.raw[:0]
+	for _, m := range r.raw {
+		if !m.ReceivedAt.Before(before) {
+			kept = append(kept, m)
+		}
+	}
+
+	deleted := int64(len(r.raw) - len(kept))
+	r.raw = kept
+	return deleted, nil
+}
+
+func (r *MemoryRepo) Close() error {
+	return nil
+}
+
+func parseDateCreated(o *models.Order) time.Time {
+	t, _ := time.Parse(time.RFC3339, o.DateCreated)
+	return t
+}
+
+// копия, чтобы вызывающий код не мог изменить хранимый заказ
+func cloneOrder(o *models.Order) *models.Order {
+	c := *o
+	if o.Items != nil {
+		c.Items = make([]models.Item, len(o.Items))
+		copy(c.Items, o.Items)
+	}
+	return &c
+}
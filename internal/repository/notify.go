@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/torrentxok/order_service/internal/config"
+	"go.uber.org/zap"
+)
+
+const orderChangesChannel = "order_changes"
+
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+	// OpEvict - заказ не менялся, но его копии в кэшах нужно выбросить
+	OpEvict = "evict"
+	// OpReset - события могли быть потеряны, подписчик сбрасывает всё, что о них знает
+	OpReset = "reset"
+)
+
+// OrderChange - payload NOTIFY в канале order_changes
+type OrderChange struct {
+	Op       string `json:"op"`
+	OrderUID string `json:"order_uid"`
+}
+
+// ChangeNotifier доставляет изменения заказов, сделанные любым инстансом.
+// Канал закрывается при отмене ctx или потере подписки.
+type ChangeNotifier interface {
+	Listen(ctx context.Context) (<-chan OrderChange, error)
+}
+
+// ChangePublisher рассылает изменение всем подписчикам ChangeNotifier,
+// когда оно не связано с записью в БД (например, ручной сброс кэша)
+type ChangePublisher interface {
+	PublishChange(ctx context.Context, change OrderChange) error
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// notifyChange отправляет NOTIFY в рамках транзакции: подписчики получат его только после commit
+func (r *OrderRepo) notifyChange(ctx context.Context, tx *sql.Tx, op, orderUID string) error {
+	return r.notify(ctx, tx, "notifyChange", OrderChange{Op: op, OrderUID: orderUID})
+}
+
+func (r *OrderRepo) PublishChange(ctx context.Context, change OrderChange) error {
+	if err := r.notify(ctx, r.db, "PublishChange", change); err != nil {
+		return mapError(err)
+	}
+	return nil
+}
+
+func (r *OrderRepo) notify(ctx context.Context, db execer, name string, change OrderChange) error {
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	query := `SELECT pg_notify($1, $2)`
+	done := r.observe(name, query, orderChangesChannel, string(payload))
+	_, err = db.ExecContext(ctx, query, orderChangesChannel, string(payload))
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to notify order change", zap.String("order_uid", change.OrderUID), zap.String("op", change.Op), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// PgNotifier слушает order_changes через LISTEN, переподключение делает pq.Listener
+type PgNotifier struct {
+	dsn    string
+	logger *zap.Logger
+}
+
+func NewPgNotifier(cfg config.DBConfig, logger *zap.Logger) *PgNotifier {
+	return &PgNotifier{
+		dsn:    buildDSN(cfg),
+		logger: logger,
+	}
+}
+
+func (n *PgNotifier) Listen(ctx context.Context) (<-chan OrderChange, error) {
+	listener := pq.NewListener(n.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			n.logger.Warn("order changes listener disconnected", zap.Error(err))
+		case pq.ListenerEventReconnected:
+			n.logger.Info("order changes listener reconnected")
+		case pq.ListenerEventConnectionAttemptFailed:
+			n.logger.Warn("order changes listener reconnect failed", zap.Error(err))
+		}
+	})
+
+	if err := listener.Listen(orderChangesChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	out := make(chan OrderChange, 64)
+
+	go func() {
+		defer close(out)
+		defer listener.Close()
+
+		ping := time.NewTicker(time.Minute)
+		defer ping.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ping.C:
+				go listener.Ping()
+			case msg := <-listener.Notify:
+				var change OrderChange
+				if msg == nil {
+					// nil приходит после переподключения: события за время разрыва потеряны
+					n.logger.Warn("order changes may have been missed during reconnect")
+					change.Op = OpReset
+				} else if err := json.Unmarshal([]byte(msg.Extra), &change); err != nil {
+					n.logger.Warn("invalid order change payload", zap.String("payload", msg.Extra), zap.Error(err))
+					continue
+				}
+
+				select {
+				case out <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
@@ -11,82 +11,99 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/torrentxok/order_service/internal/config"
 	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/pii"
 	"go.uber.org/zap"
 )
 
 type OrderRepo struct {
-	db     *sqlx.DB
-	logger *zap.Logger
+	db        *sqlx.DB
+	replicas  *replicaSet
+	cipher    *pii.Cipher
+	slowQuery time.Duration
+	logger    *zap.Logger
+
+	// строки, которые не удалось перешифровать; до перезапуска не выбираются
+	reencryptSkipped skippedRows
 }
 
-func NewRepository(cfg config.DBConfig, logger *zap.Logger) (*OrderRepo, error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name,
-	)
-
-	db, err := sqlx.Open("postgres", dsn)
+func NewRepository(ctx context.Context, cfg config.DBConfig, logger *zap.Logger) (*OrderRepo, error) {
+	db, err := connect(ctx, cfg, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := db.PingContext(ctx); err != nil {
-		return nil, fmt.Errorf("db ping failed: %w", err)
+	replicas, err := newReplicaSet(cfg, logger)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("replicas setup failed: %w", err)
 	}
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
-
-	logger.Info("database connected")
+	logger.Info("database connected", zap.Int("replicas", len(cfg.Replicas)))
 
 	return &OrderRepo{
-		db:     db,
-		logger: logger,
+		db:        db,
+		replicas:  replicas,
+		slowQuery: cfg.SlowQueryThreshold,
+		logger:    logger,
 	}, nil
 }
 
+// reader - здоровая реплика, если есть, иначе primary
+func (r *OrderRepo) reader() *sqlx.DB {
+	if rep := r.replicas.pick(); rep != nil {
+		return rep.db
+	}
+	return r.db
+}
+
 func (r *OrderRepo) CreateOrder(ctx context.Context, o *models.Order) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		r.logger.Error("failed to begin transaction", zap.Error(err))
-		return err
+		return mapError(err)
 	}
 
 	// order
 	if err := r.insertOrder(ctx, tx, o); err != nil {
 		tx.Rollback()
-		return err
+		return mapError(err)
 	}
 
 	// delivery
-	if err := r.insertDelivery(ctx, tx, o.OrderUID, &o.Delivery); err != nil {
+	if err := r.insertDelivery(ctx, tx, o.OrderUID, o.DateCreated, &o.Delivery); err != nil {
 		tx.Rollback()
-		return err
+		return mapError(err)
 	}
 
 	// payment
-	if err := r.insertPayment(ctx, tx, o.OrderUID, &o.Payment); err != nil {
+	if err := r.insertPayment(ctx, tx, o.OrderUID, o.DateCreated, &o.Payment); err != nil {
 		tx.Rollback()
-		return err
+		return mapError(err)
 	}
 
 	// items
-	if err := r.insertItems(ctx, tx, o.OrderUID, o.Items); err != nil {
+	if err := r.insertItems(ctx, tx, o.OrderUID, o.DateCreated, o.Items); err != nil {
 		tx.Rollback()
-		return err
+		return mapError(err)
+	}
+
+	if err := r.notifyChange(ctx, tx, OpCreate, o.OrderUID); err != nil {
+		tx.Rollback()
+		return mapError(err)
 	}
 
 	if err := tx.Commit(); err != nil {
 		r.logger.Error("failed to commit transaction", zap.Error(err))
-		return err
+		return mapError(err)
 	}
 	return nil
 }
 
 func (r *OrderRepo) insertOrder(ctx context.Context, tx *sql.Tx, o *models.Order) error {
+	if err := r.reserveOrderUID(ctx, tx, o.OrderUID, o.DateCreated); err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO orders (
 			order_uid, track_number, entry, locale,
@@ -95,7 +112,7 @@ func (r *OrderRepo) insertOrder(ctx context.Context, tx *sql.Tx, o *models.Order
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`
 
-	_, err := tx.ExecContext(ctx, query,
+	args := []any{
 		o.OrderUID,
 		o.TrackNumber,
 		o.Entry,
@@ -107,51 +124,97 @@ func (r *OrderRepo) insertOrder(ctx context.Context, tx *sql.Tx, o *models.Order
 		o.SmID,
 		o.DateCreated,
 		o.OofShard,
-	)
+	}
+
+	done := r.observe("insertOrder", query, args...)
+	_, err := tx.ExecContext(ctx, query, args...)
+	done(err)
 
 	if err != nil {
+		err = mapError(err)
+		if errors.Is(err, ErrOrderExists) {
+			return err
+		}
+
 		r.logger.Error("insertOrder failed", zap.Error(err))
 		return err
 	}
 	return nil
 }
 
-func (r *OrderRepo) insertDelivery(ctx context.Context, tx *sql.Tx, orderUID string, d *models.Delivery) error {
+// reserveOrderUID - уникальность order_uid по всем партициям, см. order_uids в model.sql
+func (r *OrderRepo) reserveOrderUID(ctx context.Context, tx *sql.Tx, orderUID, dateCreated string) error {
+	query := `INSERT INTO order_uids (order_uid, date_created) VALUES ($1, $2)`
+
+	done := r.observe("reserveOrderUID", query, orderUID, dateCreated)
+	_, err := tx.ExecContext(ctx, query, orderUID, dateCreated)
+	done(err)
+
+	if err != nil {
+		err = mapError(err)
+		if errors.Is(err, ErrOrderExists) {
+			return err
+		}
+
+		r.logger.Error("reserveOrderUID failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *OrderRepo) insertDelivery(ctx context.Context, tx *sql.Tx, orderUID, dateCreated string, d *models.Delivery) error {
 	query := `
 		INSERT INTO delivery (
-			order_uid, name, phone, zip,
-			city, address, region, email
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			order_uid, date_created, name, phone, zip,
+			city, address, region, email,
+			key_id, wrapped_dek, phone_bidx, email_bidx
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
-	_, err := tx.ExecContext(ctx, query,
+	sd, err := r.sealDelivery(d)
+	if err != nil {
+		r.logger.Error("failed to encrypt delivery", zap.Error(err))
+		return err
+	}
+
+	args := []any{
 		orderUID,
-		d.Name,
-		d.Phone,
-		d.Zip,
-		d.City,
-		d.Address,
-		d.Region,
-		d.Email,
-	)
+		dateCreated,
+		sd.Name,
+		sd.Phone,
+		sd.Zip,
+		sd.City,
+		sd.Address,
+		sd.Region,
+		sd.Email,
+		sd.KeyID,
+		sd.WrappedDEK,
+		sd.PhoneIndex,
+		sd.EmailIndex,
+	}
+
+	done := r.observe("insertDelivery", query, args...)
+	_, err = tx.ExecContext(ctx, query, args...)
+	done(err)
 
 	if err != nil {
 		r.logger.Error("insertDelivery failed", zap.Error(err))
-		return err
+		return mapError(err)
 	}
 	return nil
 }
 
-func (r *OrderRepo) insertPayment(ctx context.Context, tx *sql.Tx, orderUID string, p *models.Payment) error {
+func (r *OrderRepo) insertPayment(ctx context.Context, tx *sql.Tx, orderUID, dateCreated string, p *models.Payment) error {
 	query := `
 		INSERT INTO payment (
-			order_uid, transaction, request_id, currency, provider, amount,
+			order_uid, date_created, transaction, request_id, currency, provider, amount,
 			payment_dt, bank, delivery_cost, goods_total, custom_fee
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
 
-	_, err := tx.ExecContext(ctx, query,
+	args := []any{
 		orderUID,
+		dateCreated,
 		p.Transaction,
 		p.RequestID,
 		p.Currency,
@@ -162,27 +225,32 @@ func (r *OrderRepo) insertPayment(ctx context.Context, tx *sql.Tx, orderUID stri
 		p.DeliveryCost,
 		p.GoodsTotal,
 		p.CustomFee,
-	)
+	}
+
+	done := r.observe("insertPayment", query, args...)
+	_, err := tx.ExecContext(ctx, query, args...)
+	done(err)
 
 	if err != nil {
 		r.logger.Error("insertPayment failed", zap.Error(err))
-		return err
+		return mapError(err)
 	}
 
 	return nil
 }
 
-func (r *OrderRepo) insertItems(ctx context.Context, tx *sql.Tx, orderUID string, items []models.Item) error {
+func (r *OrderRepo) insertItems(ctx context.Context, tx *sql.Tx, orderUID, dateCreated string, items []models.Item) error {
 	query := `
 		INSERT INTO items (
-    		order_uid, chrt_id, track_number, price,
+    		order_uid, date_created, chrt_id, track_number, price,
     		rid, name, sale, size, total_price,
     		nm_id, brand, status
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 	for _, it := range items {
-		_, err := tx.ExecContext(ctx, query,
+		args := []any{
 			orderUID,
+			dateCreated,
 			it.ChrtID,
 			it.TrackNumber,
 			it.Price,
@@ -194,17 +262,38 @@ func (r *OrderRepo) insertItems(ctx context.Context, tx *sql.Tx, orderUID string
 			it.NmID,
 			it.Brand,
 			it.Status,
-		)
+		}
+
+		done := r.observe("insertItems", query, args...)
+		_, err := tx.ExecContext(ctx, query, args...)
+		done(err)
 		if err != nil {
 			r.logger.Error("insertItems failed", zap.Error(err))
-			return err
+			return mapError(err)
 		}
 	}
 
 	return nil
 }
 
+// GetOrder читает с реплики; если реплика ещё не получила только что
+// записанный заказ или недоступна, повторяет чтение на primary
 func (r *OrderRepo) GetOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	db := r.reader()
+
+	order, err := r.getOrder(ctx, db, orderUID)
+	if err == nil || db == r.db || ctx.Err() != nil {
+		return order, err
+	}
+
+	if !errors.Is(err, ErrOrderNotFound) {
+		r.logger.Warn("replica read failed, falling back to primary", zap.String("order_uid", orderUID), zap.Error(err))
+	}
+
+	return r.getOrder(ctx, r.db, orderUID)
+}
+
+func (r *OrderRepo) getOrder(ctx context.Context, db *sqlx.DB, orderUID string) (*models.Order, error) {
 	var order models.Order
 
 	// order
@@ -216,27 +305,39 @@ func (r *OrderRepo) GetOrder(ctx context.Context, orderUID string) (*models.Orde
 		WHERE order_uid = $1
 	`
 
-	err := r.db.GetContext(ctx, &order, queryOrder, orderUID)
+	done := r.observe("getOrder", queryOrder, orderUID)
+	err := db.GetContext(ctx, &order, queryOrder, orderUID)
+	done(err)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrOrderNotFound
 		}
 
 		r.logger.Error("failed to fetch order", zap.String("order_uid", orderUID), zap.Error(err))
-		return nil, err
+		return nil, mapError(err)
 	}
 
 	// delivery
 	queryDelivery := `
 		SELECT
-			name, phone, zip, city, address, region, email
+			order_uid, name, phone, zip, city, address, region, email,
+			key_id, wrapped_dek
 		FROM delivery
 		WHERE order_uid = $1
 	`
 
-	err = r.db.GetContext(ctx, &order.Delivery, queryDelivery, orderUID)
+	var delivery deliveryRow
+	done = r.observe("getDelivery", queryDelivery, orderUID)
+	err = db.GetContext(ctx, &delivery, queryDelivery, orderUID)
+	done(err)
 	if err != nil {
 		r.logger.Error("failed to fetch delivery", zap.String("order_uid", orderUID), zap.Error(err))
+		return nil, mapError(err)
+	}
+
+	order.Delivery, err = r.openDelivery(&delivery)
+	if err != nil {
+		r.logger.Error("failed to decrypt delivery", zap.String("order_uid", orderUID), zap.Error(err))
 		return nil, err
 	}
 
@@ -248,10 +349,12 @@ func (r *OrderRepo) GetOrder(ctx context.Context, orderUID string) (*models.Orde
 		WHERE order_uid = $1
 	`
 
-	err = r.db.GetContext(ctx, &order.Payment, queryPayment, orderUID)
+	done = r.observe("getPayment", queryPayment, orderUID)
+	err = db.GetContext(ctx, &order.Payment, queryPayment, orderUID)
+	done(err)
 	if err != nil {
 		r.logger.Error("failed to fetch payment", zap.String("order_uid", orderUID), zap.Error(err))
-		return nil, err
+		return nil, mapError(err)
 	}
 
 	// items
@@ -265,10 +368,12 @@ func (r *OrderRepo) GetOrder(ctx context.Context, orderUID string) (*models.Orde
 
 	var items []models.Item
 
-	err = r.db.SelectContext(ctx, &items, queryItems, orderUID)
+	done = r.observe("getItems", queryItems, orderUID)
+	err = db.SelectContext(ctx, &items, queryItems, orderUID)
+	done(err)
 	if err != nil {
 		r.logger.Error("failed to fetch items", zap.String("order_uid", orderUID), zap.Error(err))
-		return nil, err
+		return nil, mapError(err)
 	}
 
 	order.Items = items
@@ -286,16 +391,18 @@ func (r *OrderRepo) Exists(ctx context.Context, orderUID string) (bool, error) {
 	`
 
 	var exists bool
+	done := r.observe("Exists", query, orderUID)
 	err := r.db.QueryRowContext(ctx, query, orderUID).Scan(&exists)
+	done(err)
 	if err != nil {
 		r.logger.Error("failed to check order existence",
 			zap.Error(err),
 			zap.String("order_uid", orderUID),
 		)
-		return false, err
+		return false, mapError(err)
 	}
 
-	return exists, err
+	return exists, nil
 }
 
 func (r *OrderRepo) GetLastOrders(ctx context.Context, limit int) ([]*models.Order, error) {
@@ -306,10 +413,12 @@ func (r *OrderRepo) GetLastOrders(ctx context.Context, limit int) ([]*models.Ord
 		LIMIT $1
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit)
+	done := r.observe("GetLastOrders", query, limit)
+	rows, err := r.reader().QueryContext(ctx, query, limit)
+	done(err)
 	if err != nil {
 		r.logger.Error("failed to get last orders uids", zap.Error(err))
-		return nil, err
+		return nil, mapError(err)
 	}
 	defer rows.Close()
 
@@ -319,12 +428,12 @@ func (r *OrderRepo) GetLastOrders(ctx context.Context, limit int) ([]*models.Ord
 		var orderUID string
 
 		if err = rows.Scan(&orderUID); err != nil {
-			return nil, err
+			return nil, mapError(err)
 		}
 
 		order, err := r.GetOrder(ctx, orderUID)
 		if err != nil {
-			return nil, err
+			return nil, mapError(err)
 		}
 
 		orders = append(orders, order)
@@ -333,6 +442,57 @@ func (r *OrderRepo) GetLastOrders(ctx context.Context, limit int) ([]*models.Ord
 	return orders, nil
 }
 
+// DeleteOrder удаляет заказ, связанные строки уходят по ON DELETE CASCADE
+func (r *OrderRepo) DeleteOrder(ctx context.Context, orderUID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return mapError(err)
+	}
+
+	query := `DELETE FROM orders WHERE order_uid = $1`
+	done := r.observe("DeleteOrder", query, orderUID)
+	res, err := tx.ExecContext(ctx, query, orderUID)
+	done(err)
+	if err != nil {
+		tx.Rollback()
+		r.logger.Error("failed to delete order", zap.String("order_uid", orderUID), zap.Error(err))
+		return mapError(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return mapError(err)
+	}
+	if affected == 0 {
+		tx.Rollback()
+		return ErrOrderNotFound
+	}
+
+	query = `DELETE FROM order_uids WHERE order_uid = $1`
+	done = r.observe("DeleteOrder", query, orderUID)
+	_, err = tx.ExecContext(ctx, query, orderUID)
+	done(err)
+	if err != nil {
+		tx.Rollback()
+		r.logger.Error("failed to release order uid", zap.String("order_uid", orderUID), zap.Error(err))
+		return mapError(err)
+	}
+
+	if err := r.notifyChange(ctx, tx, OpDelete, orderUID); err != nil {
+		tx.Rollback()
+		return mapError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return mapError(err)
+	}
+	return nil
+}
+
 func (r *OrderRepo) Close() error {
+	r.replicas.close()
 	return r.db.Close()
 }
@@ -0,0 +1,409 @@
+package repository
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/torrentxok/order_service/internal/config"
+	"go.uber.org/zap"
+)
+
+// порядок важен: дочерние таблицы отсоединяются раньше orders из-за FK
+var partitionedTables = []string{"items", "payment", "delivery", "orders"}
+
+const partitionSuffixLayout = "y2006m01"
+
+// PartitionManager создаёт месячные партиции заранее и архивирует старые
+type PartitionManager struct {
+	repo   *OrderRepo
+	cfg    config.PartitionConfig
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewPartitionManager(repo *OrderRepo, cfg config.PartitionConfig, logger *zap.Logger) *PartitionManager {
+	return &PartitionManager{
+		repo:   repo,
+		cfg:    cfg,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Run выполняет оба шага обслуживания
+func (m *PartitionManager) Run(ctx context.Context) error {
+	if err := m.EnsurePartitions(ctx); err != nil {
+		return err
+	}
+
+	return m.ApplyRetention(ctx)
+}
+
+// EnsurePartitions создаёт партиции с текущего месяца на Premake месяцев вперёд.
+// Строки месяца, успевшие попасть в DEFAULT, переносятся в новую партицию.
+// Ошибка одного месяца не мешает создать остальные.
+func (m *PartitionManager) EnsurePartitions(ctx context.Context) error {
+	start := monthStart(m.now())
+
+	var errs []error
+	for i := 0; i <= m.cfg.Premake; i++ {
+		if err := m.ensureMonth(ctx, start.AddDate(0, i, 0)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	m.logger.Info("partitions ensured", zap.Int("months_ahead", m.cfg.Premake))
+	return nil
+}
+
+// ensureMonth создаёт партиции месяца в одной транзакции. Партицию нельзя создать,
+// пока в DEFAULT лежат строки из её диапазона, поэтому они сначала убираются
+// во временные таблицы, а после создания партиций вставляются обратно.
+func (m *PartitionManager) ensureMonth(ctx context.Context, from time.Time) error {
+	to := from.AddDate(0, 1, 0)
+
+	missing, err := m.monthMissing(ctx, from)
+	if err != nil || !missing {
+		return err
+	}
+
+	tx, err := m.repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// orders первой, на неё ссылаются FK остальных; блокировка сразу, иначе
+	// строки, вставленные между переносом и созданием партиции, остались бы в DEFAULT
+	for j := len(partitionedTables) - 1; j >= 0; j-- {
+		query := "LOCK TABLE " + pq.QuoteIdentifier(partitionedTables[j]+"_default") + " IN ACCESS EXCLUSIVE MODE"
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("lock default partition of %s: %w", partitionedTables[j], err)
+		}
+	}
+
+	// дочерние первыми: удаление из orders_default каскадом удалило бы их строки
+	moved := 0
+	for _, table := range partitionedTables {
+		tmp := pq.QuoteIdentifier("moved_" + table)
+
+		query := fmt.Sprintf("CREATE TEMP TABLE %s (LIKE %s) ON COMMIT DROP", tmp, pq.QuoteIdentifier(table))
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("create temp table for %s: %w", table, err)
+		}
+
+		query = fmt.Sprintf(`
+			WITH moved AS (
+				DELETE FROM %s WHERE date_created >= $1 AND date_created < $2 RETURNING *
+			)
+			INSERT INTO %s SELECT * FROM moved`,
+			pq.QuoteIdentifier(table+"_default"), tmp,
+		)
+		res, err := tx.ExecContext(ctx, query, from, to)
+		if err != nil {
+			return fmt.Errorf("move %s rows out of default partition: %w", table, err)
+		}
+		if table == "orders" {
+			n, _ := res.RowsAffected()
+			moved = int(n)
+		}
+	}
+
+	for j := len(partitionedTables) - 1; j >= 0; j-- {
+		table := partitionedTables[j]
+		name := partitionName(table, from)
+
+		query := fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM (%s) TO (%s)",
+			pq.QuoteIdentifier(name),
+			pq.QuoteIdentifier(table),
+			pq.QuoteLiteral(from.Format(time.RFC3339)),
+			pq.QuoteLiteral(to.Format(time.RFC3339)),
+		)
+
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			m.logger.Error("failed to create partition", zap.String("partition", name), zap.Error(err))
+			return fmt.Errorf("create partition %s: %w", name, err)
+		}
+	}
+
+	for j := len(partitionedTables) - 1; j >= 0; j-- {
+		table := partitionedTables[j]
+
+		// генерируемые столбцы (search_vector) вставлять нельзя
+		var columns string
+		query := `
+			SELECT string_agg(quote_ident(attname), ', ' ORDER BY attnum)
+			FROM pg_attribute
+			WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped AND attgenerated = ''
+		`
+		if err := tx.QueryRowContext(ctx, query, table).Scan(&columns); err != nil {
+			return fmt.Errorf("list columns of %s: %w", table, err)
+		}
+
+		query = fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+			pq.QuoteIdentifier(table), columns, columns, pq.QuoteIdentifier("moved_"+table))
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("move %s rows into partition: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if moved > 0 {
+		m.logger.Warn("orders moved out of default partition",
+			zap.String("month", from.Format("2006-01")),
+			zap.Int("orders", moved),
+		)
+	}
+	return nil
+}
+
+// monthMissing - не хватает хотя бы одной партиции месяца
+func (m *PartitionManager) monthMissing(ctx context.Context, month time.Time) (bool, error) {
+	for _, table := range partitionedTables {
+		var exists bool
+		err := m.repo.db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", partitionName(table, month)).Scan(&exists)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ApplyRetention отсоединяет партиции старше RetentionMonths,
+// выгружает их в ArchiveDir и только после этого удаляет
+func (m *PartitionManager) ApplyRetention(ctx context.Context) error {
+	if m.cfg.RetentionMonths <= 0 {
+		return nil
+	}
+
+	cutoff := monthStart(m.now()).AddDate(0, -m.cfg.RetentionMonths, 0)
+
+	months, err := m.expiredMonths(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.cfg.ArchiveDir, 0o755); err != nil {
+		return fmt.Errorf("create archive dir: %w", err)
+	}
+
+	for _, month := range months {
+		for _, table := range partitionedTables {
+			if err := m.archivePartition(ctx, table, partitionName(table, month)); err != nil {
+				return err
+			}
+		}
+
+		if err := m.releaseOrderUIDs(ctx, month); err != nil {
+			return err
+		}
+	}
+
+	m.logger.Info("retention applied", zap.Int("archived_months", len(months)))
+	return nil
+}
+
+// releaseOrderUIDs убирает из order_uids заказы архивированного месяца
+func (m *PartitionManager) releaseOrderUIDs(ctx context.Context, month time.Time) error {
+	query := `DELETE FROM order_uids WHERE date_created >= $1 AND date_created < $2`
+
+	if _, err := m.repo.db.ExecContext(ctx, query, month, month.AddDate(0, 1, 0)); err != nil {
+		m.logger.Error("failed to release order uids", zap.Time("month", month), zap.Error(err))
+		return fmt.Errorf("release order uids %s: %w", month.Format("2006-01"), err)
+	}
+
+	return nil
+}
+
+func (m *PartitionManager) expiredMonths(ctx context.Context, cutoff time.Time) ([]time.Time, error) {
+	partitions, err := m.listPartitions(ctx, "orders")
+	if err != nil {
+		return nil, err
+	}
+
+	var months []time.Time
+	for _, name := range partitions {
+		month, ok := partitionMonth("orders", name)
+		if !ok {
+			continue
+		}
+
+		if month.Before(cutoff) {
+			months = append(months, month)
+		}
+	}
+
+	return months, nil
+}
+
+func (m *PartitionManager) listPartitions(ctx context.Context, table string) ([]string, error) {
+	query := `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		WHERE p.relname = $1
+		ORDER BY c.relname
+	`
+
+	var names []string
+	if err := m.repo.db.SelectContext(ctx, &names, query, table); err != nil {
+		m.logger.Error("failed to list partitions", zap.String("table", table), zap.Error(err))
+		return nil, err
+	}
+
+	return names, nil
+}
+
+func (m *PartitionManager) archivePartition(ctx context.Context, table, name string) error {
+	var exists bool
+	err := m.repo.db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", name).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	// может быть уже отсоединена прошлым прерванным запуском
+	attached, err := m.isAttached(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if attached {
+		query := fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s",
+			pq.QuoteIdentifier(table), pq.QuoteIdentifier(name))
+
+		if _, err := m.repo.db.ExecContext(ctx, query); err != nil {
+			m.logger.Error("failed to detach partition", zap.String("partition", name), zap.Error(err))
+			return fmt.Errorf("detach partition %s: %w", name, err)
+		}
+	}
+
+	path, err := m.exportPartition(ctx, name)
+	if err != nil {
+		m.logger.Error("failed to export partition", zap.String("partition", name), zap.Error(err))
+		return fmt.Errorf("export partition %s: %w", name, err)
+	}
+
+	if _, err := m.repo.db.ExecContext(ctx, "DROP TABLE "+pq.QuoteIdentifier(name)); err != nil {
+		m.logger.Error("failed to drop partition", zap.String("partition", name), zap.Error(err))
+		return fmt.Errorf("drop partition %s: %w", name, err)
+	}
+
+	m.logger.Info("partition archived", zap.String("partition", name), zap.String("path", path))
+	return nil
+}
+
+func (m *PartitionManager) isAttached(ctx context.Context, name string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM pg_inherits i
+			JOIN pg_class c ON c.oid = i.inhrelid
+			WHERE c.relname = $1
+		)
+	`
+
+	var attached bool
+	err := m.repo.db.QueryRowContext(ctx, query, name).Scan(&attached)
+	return attached, err
+}
+
+// exportPartition пишет строки партиции в <ArchiveDir>/<name>.ndjson.gz
+func (m *PartitionManager) exportPartition(ctx context.Context, name string) (string, error) {
+	path := filepath.Join(m.cfg.ArchiveDir, name+".ndjson.gz")
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	bw := bufio.NewWriter(zw)
+
+	rows, err := m.repo.db.QueryContext(ctx,
+		fmt.Sprintf("SELECT row_to_json(t)::text FROM %s t", pq.QuoteIdentifier(name)))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+
+		bw.WriteString(line)
+		bw.WriteByte('\n')
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func partitionName(table string, month time.Time) string {
+	return table + "_" + month.Format(partitionSuffixLayout)
+}
+
+func partitionMonth(table, name string) (time.Time, bool) {
+	suffix, ok := strings.CutPrefix(name, table+"_")
+	if !ok {
+		return time.Time{}, false
+	}
+
+	month, err := time.Parse(partitionSuffixLayout, suffix)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return month, true
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/models"
+	"go.uber.org/zap"
+)
+
+// rawMessageRow - сообщение вместе с параметрами шифрования value
+type rawMessageRow struct {
+	models.RawMessage
+	KeyID      sql.NullString `db:"key_id"`
+	WrappedDEK []byte         `db:"wrapped_dek"`
+}
+
+// SaveRawMessage при включённом шифровании хранит value зашифрованным:
+// в теле заказа те же ПДн получателя, что и в delivery.
+// При ротации DEK переводится на текущий ключ, см. ReencryptRawMessages.
+func (r *OrderRepo) SaveRawMessage(ctx context.Context, msg *models.RawMessage) error {
+	query := `
+		INSERT INTO raw_messages (
+			order_uid, topic, kafka_partition, kafka_offset,
+			key, headers, value, received_at, key_id, wrapped_dek
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`
+
+	value := msg.Value
+	var (
+		keyID      *string
+		wrappedDEK []byte
+	)
+	if r.cipher != nil {
+		sealed, err := r.cipher.Seal(string(msg.Value))
+		if err != nil {
+			r.logger.Error("failed to encrypt raw message", zap.Error(err))
+			return err
+		}
+		value, keyID, wrappedDEK = []byte(sealed.Fields[0]), &sealed.KeyID, sealed.WrappedDEK
+	}
+
+	args := []any{
+		msg.OrderUID,
+		msg.Topic,
+		msg.Partition,
+		msg.Offset,
+		msg.Key,
+		msg.Headers,
+		value,
+		msg.ReceivedAt,
+		keyID,
+		wrappedDEK,
+	}
+
+	done := r.observe("SaveRawMessage", query, args...)
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&msg.ID)
+	done(err)
+
+	if err != nil {
+		r.logger.Error("failed to save raw message",
+			zap.String("topic", msg.Topic),
+			zap.Int("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.Error(err),
+		)
+		return mapError(err)
+	}
+	return nil
+}
+
+func (r *OrderRepo) GetRawMessages(ctx context.Context, orderUID string) ([]*models.RawMessage, error) {
+	query := `
+		SELECT	id, order_uid, topic, kafka_partition, kafka_offset,
+				key, headers, value, received_at, key_id, wrapped_dek
+		FROM raw_messages
+		WHERE order_uid = $1
+		ORDER BY received_at
+	`
+
+	var rows []rawMessageRow
+	done := r.observe("GetRawMessages", query, orderUID)
+	err := r.db.SelectContext(ctx, &rows, query, orderUID)
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to fetch raw messages", zap.String("order_uid", orderUID), zap.Error(err))
+		return nil, mapError(err)
+	}
+
+	msgs := make([]*models.RawMessage, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		if row.KeyID.Valid {
+			if r.cipher == nil {
+				return nil, errNoCipher
+			}
+
+			plain, err := r.cipher.Open(row.KeyID.String, row.WrappedDEK, string(row.Value))
+			if err != nil {
+				r.logger.Error("failed to decrypt raw message", zap.Int64("id", row.ID), zap.Error(err))
+				return nil, err
+			}
+			row.Value = []byte(plain[0])
+		}
+		msgs = append(msgs, &row.RawMessage)
+	}
+
+	return msgs, nil
+}
+
+func (r *OrderRepo) DeleteRawMessagesBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM raw_messages WHERE received_at < $1`
+	done := r.observe("DeleteRawMessagesBefore", query, before)
+	res, err := r.db.ExecContext(ctx, query, before)
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to delete raw messages", zap.Error(err))
+		return 0, mapError(err)
+	}
+
+	return res.RowsAffected()
+}
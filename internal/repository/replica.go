@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/torrentxok/order_service/internal/config"
+	"go.uber.org/zap"
+)
+
+type replica struct {
+	addr    string
+	db      *sqlx.DB
+	healthy atomic.Bool
+}
+
+// replicaSet отдаёт реплики, которые отвечают и отстают не больше maxLag
+type replicaSet struct {
+	replicas []*replica
+	maxLag   time.Duration
+	next     atomic.Uint64
+	logger   *zap.Logger
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newReplicaSet(cfg config.DBConfig, logger *zap.Logger) (*replicaSet, error) {
+	s := &replicaSet{
+		maxLag: cfg.ReplicaMaxLag,
+		logger: logger,
+		stop:   make(chan struct{}),
+	}
+
+	for _, addr := range cfg.Replicas {
+		replicaCfg, err := replicaConfig(cfg, addr)
+		if err != nil {
+			s.closeAll()
+			return nil, fmt.Errorf("replica %s: %w", addr, err)
+		}
+
+		// без ожидания: недоступную реплику отметит проверка здоровья
+		db, err := openDB(replicaCfg)
+		if err != nil {
+			s.closeAll()
+			return nil, err
+		}
+
+		s.replicas = append(s.replicas, &replica{addr: addr, db: db})
+	}
+
+	if len(s.replicas) == 0 {
+		return s, nil
+	}
+
+	// до первой проверки все реплики считаются недоступными
+	s.checkAll()
+
+	s.wg.Add(1)
+	go s.run(cfg.ReplicaCheckInterval)
+
+	return s, nil
+}
+
+// replicaConfig - настройки primary с адресом реплики host[:port]. Если primary
+// задан через DATABASE_URL, в нём меняются только хост и порт: пользователь,
+// пароль, база и параметры подключения остаются те же.
+func replicaConfig(cfg config.DBConfig, addr string) (config.DBConfig, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, ""
+	}
+
+	if cfg.URL == "" {
+		if port != "" {
+			if cfg.Port, err = strconv.Atoi(port); err != nil {
+				return cfg, err
+			}
+		}
+		cfg.Host = host
+		return cfg, nil
+	}
+
+	if !strings.HasPrefix(cfg.URL, "postgres://") && !strings.HasPrefix(cfg.URL, "postgresql://") {
+		// key=value: pq берёт последнее значение повторённого параметра
+		cfg.URL += " host=" + dsnValue(host)
+		if port != "" {
+			cfg.URL += " port=" + dsnValue(port)
+		}
+		return cfg, nil
+	}
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return cfg, fmt.Errorf("parse DATABASE_URL: %w", err)
+	}
+	if port == "" {
+		port = u.Port()
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else {
+		u.Host = host
+	}
+	cfg.URL = u.String()
+
+	return cfg, nil
+}
+
+// pick возвращает следующую здоровую реплику по кругу или nil
+func (s *replicaSet) pick() *replica {
+	n := len(s.replicas)
+	if n == 0 {
+		return nil
+	}
+
+	start := s.next.Add(1)
+	for i := 0; i < n; i++ {
+		rep := s.replicas[(start+uint64(i))%uint64(n)]
+		if rep.healthy.Load() {
+			return rep
+		}
+	}
+
+	return nil
+}
+
+func (s *replicaSet) run(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.checkAll()
+		}
+	}
+}
+
+func (s *replicaSet) checkAll() {
+	for _, rep := range s.replicas {
+		healthy := s.check(rep)
+		if rep.healthy.Swap(healthy) != healthy {
+			s.logger.Info("replica state changed", zap.String("replica", rep.addr), zap.Bool("healthy", healthy))
+		}
+	}
+}
+
+func (s *replicaSet) check(rep *replica) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// если всё полученное уже применено, отставания нет, даже если давно не было записей
+	query := `
+		SELECT CASE
+			WHEN NOT pg_is_in_recovery() THEN 0
+			WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END
+	`
+
+	var lagSeconds float64
+	if err := rep.db.QueryRowContext(ctx, query).Scan(&lagSeconds); err != nil {
+		s.logger.Warn("replica health check failed", zap.String("replica", rep.addr), zap.Error(err))
+		return false
+	}
+
+	lag := time.Duration(lagSeconds * float64(time.Second))
+	if s.maxLag > 0 && lag > s.maxLag {
+		s.logger.Warn("replica lag too high", zap.String("replica", rep.addr), zap.Duration("lag", lag))
+		return false
+	}
+
+	return true
+}
+
+func (s *replicaSet) close() {
+	if len(s.replicas) > 0 {
+		close(s.stop)
+		s.wg.Wait()
+	}
+	s.closeAll()
+}
+
+func (s *replicaSet) closeAll() {
+	for _, rep := range s.replicas {
+		rep.db.Close()
+	}
+}
@@ -2,7 +2,7 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"time"
 
 	"github.com/torrentxok/order_service/internal/models"
 )
@@ -12,6 +12,11 @@ type OrderRepository interface {
 	GetOrder(ctx context.Context, orderUID string) (*models.Order, error)
 	Exists(ctx context.Context, orderUID string) (bool, error)
 	GetLastOrders(ctx context.Context, limit int) ([]*models.Order, error)
+	DeleteOrder(ctx context.Context, orderUID string) error
 }
 
-var ErrOrderNotFound = errors.New("order not found")
+type RawMessageRepository interface {
+	SaveRawMessage(ctx context.Context, msg *models.RawMessage) error
+	GetRawMessages(ctx context.Context, orderUID string) ([]*models.RawMessage, error)
+	DeleteRawMessagesBefore(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/torrentxok/order_service/internal/models"
+	"go.uber.org/zap"
+)
+
+type SearchRepository interface {
+	SearchOrders(ctx context.Context, q models.SearchQuery) ([]models.SearchResult, error)
+}
+
+// SearchOrders ищет заказы по tsvector товаров и триграммам delivery.name.
+// Если задано несколько условий, заказ должен удовлетворять всем.
+// Телефон и email при шифровании ищутся через blind-индекс, а по зашифрованному
+// имени триграммы не работают - такой поиск отклоняется с ErrUnsupportedSearch.
+func (r *OrderRepo) SearchOrders(ctx context.Context, q models.SearchQuery) ([]models.SearchResult, error) {
+	if q.Name != "" && r.cipher != nil {
+		return nil, fmt.Errorf("%w: name search is unavailable while delivery PII is encrypted", ErrUnsupportedSearch)
+	}
+
+	args := []any{}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var itemConds, orderConds []string
+	if !q.From.IsZero() {
+		itemConds = append(itemConds, "i.date_created >= "+arg(q.From))
+		orderConds = append(orderConds, "o.date_created >= "+arg(q.From))
+	}
+	if !q.To.IsZero() {
+		itemConds = append(itemConds, "i.date_created < "+arg(q.To))
+		orderConds = append(orderConds, "o.date_created < "+arg(q.To))
+	}
+
+	itemsJoin, itemsRank, snippets := "", "0", "'{}'::text[]"
+	if q.Text != "" {
+		tsq := "websearch_to_tsquery('simple', " + arg(q.Text) + ")"
+		conds := append([]string{"i.search_vector @@ " + tsq}, itemConds...)
+
+		itemsJoin = fmt.Sprintf(`JOIN (
+			SELECT	i.order_uid, i.date_created,
+					MAX(ts_rank(i.search_vector, %[1]s)) AS rank,
+					array_agg(ts_headline('simple', i.brand || ' ' || i.name, %[1]s,
+						'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')) AS snippets
+			FROM items i
+			WHERE %[2]s
+			GROUP BY i.order_uid, i.date_created
+		) mi ON mi.order_uid = o.order_uid AND mi.date_created = o.date_created`,
+			tsq, strings.Join(conds, " AND "))
+		itemsRank, snippets = "mi.rank", "mi.snippets"
+	}
+
+	nameRank := "0"
+	if q.Name != "" {
+		name := arg(q.Name)
+		orderConds = append(orderConds, "d.name % "+name)
+		nameRank = "similarity(d.name, " + name + ")"
+	}
+
+	if q.Phone != "" {
+		orderConds = append(orderConds, r.contactCond("phone", q.Phone, arg))
+	}
+	if q.Email != "" {
+		orderConds = append(orderConds, r.contactCond("email", q.Email, arg))
+	}
+
+	where := "true"
+	if len(orderConds) > 0 {
+		where = strings.Join(orderConds, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT	o.order_uid, o.date_created, d.name, d.key_id, d.wrapped_dek,
+				%s + %s AS rank,
+				%s AS snippets
+		FROM orders o
+		JOIN delivery d ON d.order_uid = o.order_uid AND d.date_created = o.date_created
+		%s
+		WHERE %s
+		ORDER BY rank DESC, o.date_created DESC
+		LIMIT %s
+	`, itemsRank, nameRank, snippets, itemsJoin, where, arg(q.Limit))
+
+	done := r.observe("SearchOrders", query, args...)
+	rows, err := r.reader().QueryContext(ctx, query, args...)
+	done(err)
+	if err != nil {
+		r.logger.Error("failed to search orders", zap.Error(err))
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	for rows.Next() {
+		var res models.SearchResult
+		var found pq.StringArray
+		var delivery deliveryRow
+
+		err := rows.Scan(&res.OrderUID, &res.DateCreated,
+			&delivery.Name, &delivery.KeyID, &delivery.WrappedDEK,
+			&res.Rank, &found,
+		)
+		if err != nil {
+			return nil, mapError(err)
+		}
+
+		// name шифруется первым полем, остальные здесь не нужны
+		if delivery.KeyID.Valid {
+			if r.cipher == nil {
+				return nil, errNoCipher
+			}
+
+			plain, err := r.cipher.Open(delivery.KeyID.String, delivery.WrappedDEK, delivery.Name)
+			if err != nil {
+				return nil, err
+			}
+			delivery.Name = plain[0]
+		}
+
+		res.DeliveryName = delivery.Name
+		res.Snippets = found
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return results, nil
+}
+
+// contactCond - точное совпадение по телефону или email: по blind-индексу
+// для зашифрованных строк и по самому значению для ещё открытых
+func (r *OrderRepo) contactCond(kind, value string, arg func(any) string) string {
+	if r.cipher == nil {
+		return fmt.Sprintf("d.%s = %s", kind, arg(value))
+	}
+
+	return fmt.Sprintf("(d.%[1]s_bidx = %[2]s OR (d.key_id IS NULL AND d.%[1]s = %[3]s))",
+		kind, arg(r.cipher.BlindIndex(kind, value)), arg(value))
+}
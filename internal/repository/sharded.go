@@ -0,0 +1,461 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/torrentxok/order_service/internal/config"
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/pii"
+	"go.uber.org/zap"
+)
+
+type shard struct {
+	name string
+	cfg  config.DBConfig
+	repo *OrderRepo
+}
+
+// ShardedRepo раскладывает заказы по нескольким БД по ShardKey.
+// Поиск по order_uid, где шард неизвестен, опрашивает все шарды параллельно.
+// Запись проверяет, что order_uid не занят в других шардах, поэтому требует их доступности.
+// Перенос данных при изменении карты шардов делается отдельно.
+type ShardedRepo struct {
+	shards   []*shard
+	byName   map[string]*shard
+	shardMap map[string]string
+	logger   *zap.Logger
+}
+
+func NewShardedRepository(ctx context.Context, base config.DBConfig, cfg config.ShardConfig, logger *zap.Logger) (*ShardedRepo, error) {
+	if len(cfg.Shards) == 0 {
+		return nil, errors.New("no shards configured")
+	}
+
+	r := &ShardedRepo{
+		byName:   make(map[string]*shard, len(cfg.Shards)),
+		shardMap: cfg.Map,
+		logger:   logger,
+	}
+
+	for _, sh := range cfg.Shards {
+		if _, ok := r.byName[sh.Name]; ok {
+			r.Close()
+			return nil, fmt.Errorf("duplicate shard %q", sh.Name)
+		}
+
+		shardCfg := base
+		shardCfg.URL = sh.URL
+		shardCfg.Replicas = nil
+
+		repo, err := NewRepository(ctx, shardCfg, logger.With(zap.String("shard", sh.Name)))
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("shard %s: %w", sh.Name, err)
+		}
+
+		s := &shard{name: sh.Name, cfg: shardCfg, repo: repo}
+		r.shards = append(r.shards, s)
+		r.byName[sh.Name] = s
+	}
+
+	for key, name := range cfg.Map {
+		if _, ok := r.byName[name]; !ok {
+			r.Close()
+			return nil, fmt.Errorf("shard map: key %q points to unknown shard %q", key, name)
+		}
+	}
+
+	return r, nil
+}
+
+func (r *ShardedRepo) EnableEncryption(c *pii.Cipher) {
+	for _, s := range r.shards {
+		s.repo.EnableEncryption(c)
+	}
+}
+
+func (r *ShardedRepo) RegisterMetrics(reg prometheus.Registerer) error {
+	for _, s := range r.shards {
+		if err := s.repo.RegisterMetrics(reg, "shard/"+s.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ShardedRepo) shardFor(shardKey string) *shard {
+	if name, ok := r.shardMap[shardKey]; ok {
+		return r.byName[name]
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(shardKey))
+	return r.shards[h.Sum32()%uint32(len(r.shards))]
+}
+
+// takenElsewhere - order_uid, уже занятые в шардах, кроме target. order_uids уникален
+// только внутри шарда, а с другим ShardKey тот же заказ попал бы в другой шард.
+// Недоступный шард - ошибка: без него уникальность не гарантировать.
+func (r *ShardedRepo) takenElsewhere(ctx context.Context, target *shard, uids []string) (map[string]bool, error) {
+	var (
+		mu    sync.Mutex
+		taken = make(map[string]bool)
+	)
+
+	err := r.fanOut(ctx, func(ctx context.Context, s *shard) error {
+		if s == target {
+			return nil
+		}
+
+		existing, err := s.repo.existingOrders(ctx, uids)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		for _, uid := range existing {
+			taken[uid] = true
+		}
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return taken, nil
+}
+
+func (r *ShardedRepo) CreateOrder(ctx context.Context, o *models.Order) error {
+	target := r.shardFor(o.ShardKey)
+
+	taken, err := r.takenElsewhere(ctx, target, []string{o.OrderUID})
+	if err != nil {
+		return err
+	}
+	if taken[o.OrderUID] {
+		return ErrOrderExists
+	}
+
+	return target.repo.CreateOrder(ctx, o)
+}
+
+func (r *ShardedRepo) GetOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	var (
+		mu    sync.Mutex
+		found []*models.Order
+	)
+
+	err := r.fanOut(ctx, func(ctx context.Context, s *shard) error {
+		order, err := s.repo.GetOrder(ctx, orderUID)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		found = append(found, order)
+		mu.Unlock()
+		return nil
+	})
+
+	if len(found) > 1 {
+		r.logger.Error("order found in several shards", zap.String("order_uid", orderUID), zap.Int("shards", len(found)))
+		return nil, ErrShardConflict
+	}
+
+	// заказ лежит ровно в одном шарде: если он нашёлся, недоступность остальных не важна
+	if len(found) == 1 {
+		if err != nil {
+			r.logger.Warn("order found despite shard errors", zap.String("order_uid", orderUID), zap.Error(err))
+		}
+		return found[0], nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, ErrOrderNotFound
+}
+
+func (r *ShardedRepo) Exists(ctx context.Context, orderUID string) (bool, error) {
+	var exists atomic.Bool
+
+	err := r.fanOut(ctx, func(ctx context.Context, s *shard) error {
+		ok, err := s.repo.Exists(ctx, orderUID)
+		if err != nil {
+			return err
+		}
+		if ok {
+			exists.Store(true)
+		}
+		return nil
+	})
+
+	if exists.Load() {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+func (r *ShardedRepo) DeleteOrder(ctx context.Context, orderUID string) error {
+	var (
+		mu      sync.Mutex
+		deleted bool
+	)
+
+	err := r.fanOut(ctx, func(ctx context.Context, s *shard) error {
+		if err := s.repo.DeleteOrder(ctx, orderUID); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		deleted = true
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrOrderNotFound
+	}
+
+	return nil
+}
+
+// GetLastOrders берёт limit последних с каждого шарда и сливает их
+func (r *ShardedRepo) GetLastOrders(ctx context.Context, limit int) ([]*models.Order, error) {
+	var (
+		mu     sync.Mutex
+		orders []*models.Order
+	)
+
+	err := r.fanOut(ctx, func(ctx context.Context, s *shard) error {
+		shardOrders, err := s.repo.GetLastOrders(ctx, limit)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		orders = append(orders, shardOrders...)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(orders, func(i, j int) bool {
+		return parseDateCreated(orders[i]).After(parseDateCreated(orders[j]))
+	})
+
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+
+	return orders, nil
+}
+
+func (r *ShardedRepo) ReencryptDeliveries(ctx context.Context, batchSize int) (int, error) {
+	total := 0
+	for _, s := range r.shards {
+		n, err := s.repo.ReencryptDeliveries(ctx, batchSize)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("shard %s: %w", s.name, err)
+		}
+	}
+	return total, nil
+}
+
+// ScanOrders обходит шарды по очереди, порядок по date_created соблюдается только внутри шарда
+func (r *ShardedRepo) ScanOrders(ctx context.Context, f models.OrderFilter, batchSize int, fn func([]*models.Order) error) error {
+	for _, s := range r.shards {
+		if err := s.repo.ScanOrders(ctx, f, batchSize, fn); err != nil {
+			return fmt.Errorf("shard %s: %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+func (r *ShardedRepo) CheckConsistency(ctx context.Context, batchSize int, fn func([]models.Inconsistency) error) error {
+	for _, s := range r.shards {
+		if err := s.repo.CheckConsistency(ctx, batchSize, fn); err != nil {
+			return fmt.Errorf("shard %s: %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+// CreateOrders пишет пачку по шардам; атомарность - в пределах одного шарда
+func (r *ShardedRepo) CreateOrders(ctx context.Context, orders []*models.Order) (int, error) {
+	groups := make(map[*shard][]*models.Order)
+	for _, o := range orders {
+		s := r.shardFor(o.ShardKey)
+		groups[s] = append(groups[s], o)
+	}
+
+	created := 0
+	for _, s := range r.shards {
+		if len(groups[s]) == 0 {
+			continue
+		}
+
+		uids := make([]string, len(groups[s]))
+		for i, o := range groups[s] {
+			uids[i] = o.OrderUID
+		}
+		taken, err := r.takenElsewhere(ctx, s, uids)
+		if err != nil {
+			return created, err
+		}
+
+		// как и уже существующие в шарде, занятые в других шардах пропускаются
+		group := groups[s][:0]
+		for _, o := range groups[s] {
+			if !taken[o.OrderUID] {
+				group = append(group, o)
+			}
+		}
+		if len(group) == 0 {
+			continue
+		}
+
+		n, err := s.repo.CreateOrders(ctx, group)
+		created += n
+		if err != nil {
+			return created, fmt.Errorf("shard %s: %w", s.name, err)
+		}
+	}
+
+	return created, nil
+}
+
+func (r *ShardedRepo) PseudonymizeCustomer(ctx context.Context, customerID string, replacement models.Delivery) ([]string, error) {
+	var (
+		mu   sync.Mutex
+		uids []string
+	)
+
+	err := r.fanOut(ctx, func(ctx context.Context, s *shard) error {
+		shardUIDs, err := s.repo.PseudonymizeCustomer(ctx, customerID, replacement)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		uids = append(uids, shardUIDs...)
+		mu.Unlock()
+		return nil
+	})
+
+	return uids, err
+}
+
+// PublishChange достаточно одного шарда: Listen слушает все
+func (r *ShardedRepo) PublishChange(ctx context.Context, change OrderChange) error {
+	if err := r.shards[0].repo.PublishChange(ctx, change); err != nil {
+		return fmt.Errorf("shard %s: %w", r.shards[0].name, err)
+	}
+	return nil
+}
+
+// Listen объединяет уведомления об изменениях со всех шардов
+func (r *ShardedRepo) Listen(ctx context.Context) (<-chan OrderChange, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	subs := make([]<-chan OrderChange, 0, len(r.shards))
+	for _, s := range r.shards {
+		changes, err := NewPgNotifier(s.cfg, r.logger.With(zap.String("shard", s.name))).Listen(ctx)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("shard %s: %w", s.name, err)
+		}
+		subs = append(subs, changes)
+	}
+
+	out := make(chan OrderChange, 64)
+	var wg sync.WaitGroup
+
+	for _, changes := range subs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// обрыв одного шарда переподписывает все
+			defer cancel()
+
+			for change := range changes {
+				select {
+				case out <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(out)
+	}()
+
+	return out, nil
+}
+
+// MaintainPartitions обслуживает партиции каждого шарда. Имена партиций
+// на шардах совпадают, поэтому архив каждого шарда - в своём подкаталоге.
+// Сбой одного шарда не мешает обслужить остальные.
+func (r *ShardedRepo) MaintainPartitions(ctx context.Context, cfg config.PartitionConfig) error {
+	var errs []error
+	for _, s := range r.shards {
+		shardCfg := cfg
+		shardCfg.ArchiveDir = filepath.Join(cfg.ArchiveDir, s.name)
+
+		logger := r.logger.With(zap.String("shard", s.name))
+		if err := NewPartitionManager(s.repo, shardCfg, logger).Run(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shard %s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *ShardedRepo) Close() error {
+	var errs []error
+	for _, s := range r.shards {
+		errs = append(errs, s.repo.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// fanOut выполняет fn на всех шардах; ErrOrderNotFound шарда ошибкой не считается
+func (r *ShardedRepo) fanOut(ctx context.Context, fn func(ctx context.Context, s *shard) error) error {
+	errs := make([]error, len(r.shards))
+	var wg sync.WaitGroup
+
+	for i, s := range r.shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := fn(ctx, s); err != nil && !errors.Is(err, ErrOrderNotFound) {
+				errs[i] = fmt.Errorf("shard %s: %w", s.name, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// AuditService хранит исходные сообщения для разбора спорных заказов
+type AuditService struct {
+	repo      repository.RawMessageRepository
+	retention time.Duration
+	logger    *zap.Logger
+}
+
+func NewAuditService(repo repository.RawMessageRepository, retention time.Duration, logger *zap.Logger) *AuditService {
+	return &AuditService{
+		repo:      repo,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+func (s *AuditService) SaveRawMessage(ctx context.Context, msg *models.RawMessage) error {
+	return s.repo.SaveRawMessage(ctx, msg)
+}
+
+func (s *AuditService) GetRawMessages(ctx context.Context, orderUID string) ([]*models.RawMessage, error) {
+	msgs, err := s.repo.GetRawMessages(ctx, orderUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, ErrOrderNotFound
+	}
+
+	return msgs, nil
+}
+
+// RunCleanup периодически удаляет сообщения старше retention, пока не отменён ctx
+func (s *AuditService) RunCleanup(ctx context.Context, interval time.Duration) {
+	if s.retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := s.repo.DeleteRawMessagesBefore(ctx, time.Now().Add(-s.retention))
+		if err != nil {
+			s.logger.Warn("raw messages cleanup failed", zap.Error(err))
+		} else if deleted > 0 {
+			s.logger.Info("raw messages cleaned up", zap.Int64("deleted", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/cache"
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/repository"
+	"go.uber.org/zap"
+)
+
+var ErrResizeUnsupported = cache.ErrResizeUnsupported
+
+// CacheAdminService - ручное управление кэшем этого инстанса.
+// Evict и Purge доходят до общего L2 через сам кэш, а до других инстансов -
+// через рассылку, если она включена.
+type CacheAdminService struct {
+	cache  cache.OrderCache
+	orders *OrderService
+	// nil - сброс действует только на этот инстанс
+	publisher repository.ChangePublisher
+	logger    *zap.Logger
+}
+
+func NewCacheAdminService(c cache.OrderCache, orders *OrderService, logger *zap.Logger) *CacheAdminService {
+	return &CacheAdminService{
+		cache:  c,
+		orders: orders,
+		logger: logger,
+	}
+}
+
+// EnableBroadcast рассылает Evict и Purge остальным инстансам через CacheInvalidator
+func (s *CacheAdminService) EnableBroadcast(p repository.ChangePublisher) {
+	s.publisher = p
+}
+
+func (s *CacheAdminService) Stats() cache.Stats {
+	return s.cache.Stats()
+}
+
+// Keys - содержимое локального кэша без самих заказов
+func (s *CacheAdminService) Keys() []models.CacheEntry {
+	entries := s.cache.Entries()
+	now := time.Now()
+
+	result := make([]models.CacheEntry, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, cacheEntry(e, now))
+	}
+	return result
+}
+
+func (s *CacheAdminService) Inspect(orderUID string) (*models.CacheEntry, error) {
+	e, ok := s.cache.Peek(orderUID)
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+
+	entry := cacheEntry(e, time.Now())
+	entry.Order = e.Value
+	return &entry, nil
+}
+
+// Evict и Purge при ошибке рассылки уже выполнены локально
+func (s *CacheAdminService) Evict(ctx context.Context, orderUID, requestedBy string) error {
+	s.cache.Delete(orderUID)
+	s.logger.Info("cache entry evicted", zap.String("order_uid", orderUID), zap.String("requested_by", requestedBy))
+
+	return s.broadcast(ctx, repository.OrderChange{Op: repository.OpEvict, OrderUID: orderUID})
+}
+
+func (s *CacheAdminService) Purge(ctx context.Context, requestedBy string) error {
+	s.cache.Purge()
+	s.logger.Info("cache purged", zap.String("requested_by", requestedBy))
+
+	return s.broadcast(ctx, repository.OrderChange{Op: repository.OpReset})
+}
+
+func (s *CacheAdminService) broadcast(ctx context.Context, change repository.OrderChange) error {
+	if s.publisher == nil {
+		return nil
+	}
+	return s.publisher.PublishChange(ctx, change)
+}
+
+func (s *CacheAdminService) Warmup(ctx context.Context, requestedBy string) error {
+	s.logger.Info("cache re-warm requested", zap.String("requested_by", requestedBy))
+	return s.orders.WarmUpCache(ctx)
+}
+
+func (s *CacheAdminService) Resize(capacity int, requestedBy string) error {
+	if capacity <= 0 {
+		return fmt.Errorf("%w: capacity must be positive", ErrInvalidArgument)
+	}
+
+	r, ok := s.cache.(cache.Resizer)
+	if !ok {
+		return ErrResizeUnsupported
+	}
+
+	prev := s.cache.Capacity()
+	if err := r.Resize(capacity); err != nil {
+		if errors.Is(err, cache.ErrResizeUnsupported) {
+			return ErrResizeUnsupported
+		}
+		return err
+	}
+
+	s.logger.Info("cache resized",
+		zap.Int("from", prev),
+		zap.Int("to", s.cache.Capacity()),
+		zap.String("requested_by", requestedBy),
+	)
+	return nil
+}
+
+func cacheEntry(e cache.Entry, now time.Time) models.CacheEntry {
+	entry := models.CacheEntry{OrderUID: e.Key}
+	if !e.StoredAt.IsZero() {
+		entry.StoredAt = &e.StoredAt
+		entry.AgeSeconds = now.Sub(e.StoredAt).Seconds()
+	}
+	if !e.ExpiresAt.IsZero() {
+		entry.ExpiresAt = &e.ExpiresAt
+	}
+	return entry
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/cache"
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// otherInstance - кэш второго инстанса, который держит в актуальном состоянии инвалидатор
+func otherInstance(t *testing.T, repo *repository.MemoryRepo) cache.OrderCache {
+	t.Helper()
+
+	c := cache.NewLRUCache(10)
+	n := &listenReady{ChangeNotifier: repo, ready: make(chan struct{})}
+	inv := NewCacheInvalidator(n, repo, c, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		inv.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	<-n.ready
+	return c
+}
+
+func waitEvicted(t *testing.T, c cache.OrderCache, key string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok := c.Peek(key); !ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("key %s is still cached", key)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCacheAdminEvictBroadcasts(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	seedOrder(t, repo, "a")
+
+	other := otherInstance(t, repo)
+	other.Set("a", &models.Order{OrderUID: "a"})
+
+	local := cache.NewLRUCache(10)
+	local.Set("a", &models.Order{OrderUID: "a"})
+
+	admin := NewCacheAdminService(local, NewOrderService(repo, local, zap.NewNop()), zap.NewNop())
+	admin.EnableBroadcast(repo)
+
+	if err := admin.Evict(context.Background(), "a", "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := local.Peek("a"); ok {
+		t.Fatal("key is still cached locally")
+	}
+	waitEvicted(t, other, "a")
+
+	// сам заказ evict не трогает
+	if _, err := repo.GetOrder(context.Background(), "a"); err != nil {
+		t.Fatalf("order lost after evict: %v", err)
+	}
+}
+
+func TestCacheAdminPurgeBroadcasts(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+
+	other := otherInstance(t, repo)
+	other.Set("a", &models.Order{OrderUID: "a"})
+
+	local := cache.NewLRUCache(10)
+	admin := NewCacheAdminService(local, NewOrderService(repo, local, zap.NewNop()), zap.NewNop())
+	admin.EnableBroadcast(repo)
+
+	if err := admin.Purge(context.Background(), "test"); err != nil {
+		t.Fatal(err)
+	}
+	waitEvicted(t, other, "a")
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/cache"
+	"go.uber.org/zap"
+)
+
+// RunCacheStatsLog раз в interval пишет в лог счётчики кэша за период и накопленные
+func RunCacheStatsLog(ctx context.Context, c cache.OrderCache, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	prev := c.Stats()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cur := c.Stats()
+		period := cache.Stats{
+			Hits:        cur.Hits - prev.Hits,
+			Misses:      cur.Misses - prev.Misses,
+			Evictions:   cur.Evictions - prev.Evictions,
+			Expirations: cur.Expirations - prev.Expirations,
+		}
+		prev = cur
+
+		logger.Info("cache stats",
+			zap.Uint64("hits", period.Hits),
+			zap.Uint64("misses", period.Misses),
+			zap.Uint64("evictions", period.Evictions),
+			zap.Uint64("expirations", period.Expirations),
+			zap.Float64("hit_ratio", period.HitRatio()),
+			zap.Float64("hit_ratio_total", cur.HitRatio()),
+			zap.Int("len", cur.Len),
+			zap.Int("capacity", cur.Capacity),
+			zap.Int64("bytes", cur.Bytes),
+			zap.Int64("max_bytes", cur.MaxBytes),
+		)
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/cache"
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// GDPRService выполняет запросы субъектов ПДн: выгрузку и обезличивание
+type GDPRService struct {
+	orders    repository.OrderBulkRepository
+	customers repository.CustomerDataRepository
+	requests  repository.DataRequestRepository
+	cache     cache.OrderCache
+	logger    *zap.Logger
+}
+
+func NewGDPRService(
+	orders repository.OrderBulkRepository,
+	customers repository.CustomerDataRepository,
+	requests repository.DataRequestRepository,
+	cache cache.OrderCache,
+	logger *zap.Logger,
+) *GDPRService {
+	return &GDPRService{
+		orders:    orders,
+		customers: customers,
+		requests:  requests,
+		cache:     cache,
+		logger:    logger,
+	}
+}
+
+func (s *GDPRService) ExportCustomerData(ctx context.Context, customerID, requestedBy string) (*models.CustomerDataBundle, error) {
+	if customerID == "" {
+		return nil, fmt.Errorf("%w: customer_id is required", ErrInvalidArgument)
+	}
+
+	bundle := &models.CustomerDataBundle{
+		CustomerID:  customerID,
+		GeneratedAt: time.Now().UTC(),
+		Orders:      []*models.Order{},
+	}
+
+	err := s.orders.ScanOrders(ctx, models.OrderFilter{CustomerID: customerID}, 500, func(orders []*models.Order) error {
+		bundle.Orders = append(bundle.Orders, orders...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.record(ctx, customerID, models.DataRequestExport, requestedBy, len(bundle.Orders)); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("customer data exported",
+		zap.String("customer_id", customerID),
+		zap.Int("orders", len(bundle.Orders)),
+	)
+	return bundle, nil
+}
+
+// EraseCustomerData обезличивает доставку во всех заказах покупателя
+// и удаляет исходные сообщения; суммы и состав заказов не меняются
+func (s *GDPRService) EraseCustomerData(ctx context.Context, customerID, requestedBy string) (int, error) {
+	if customerID == "" {
+		return 0, fmt.Errorf("%w: customer_id is required", ErrInvalidArgument)
+	}
+
+	token, err := pseudonym()
+	if err != nil {
+		return 0, err
+	}
+
+	replacement := models.Delivery{
+		Name:    "erased-" + token,
+		Phone:   "erased-" + token,
+		Email:   "erased-" + token + "@invalid",
+		Address: "erased-" + token,
+	}
+
+	uids, err := s.customers.PseudonymizeCustomer(ctx, customerID, replacement)
+
+	// даже при частичной ошибке (шарды) уже изменённые заказы не должны остаться в кэше
+	for _, uid := range uids {
+		s.cache.Delete(uid)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if len(uids) > 0 {
+		if _, err := s.requests.DeleteRawMessagesForOrders(ctx, uids); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := s.record(ctx, customerID, models.DataRequestErase, requestedBy, len(uids)); err != nil {
+		return 0, err
+	}
+
+	s.logger.Info("customer data erased",
+		zap.String("customer_id", customerID),
+		zap.Int("orders", len(uids)),
+	)
+	return len(uids), nil
+}
+
+func (s *GDPRService) record(ctx context.Context, customerID, requestType, requestedBy string, affected int) error {
+	return s.requests.RecordDataRequest(ctx, &models.DataRequest{
+		CustomerID:     customerID,
+		Type:           requestType,
+		RequestedBy:    requestedBy,
+		OrdersAffected: affected,
+	})
+}
+
+func pseudonym() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
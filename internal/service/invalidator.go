@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/cache"
+	"github.com/torrentxok/order_service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// CacheInvalidator применяет к локальному кэшу изменения, сделанные другими инстансами
+type CacheInvalidator struct {
+	notifier repository.ChangeNotifier
+	repo     repository.OrderRepository
+	cache    cache.OrderCache
+	negative *cache.NegativeCache
+	logger   *zap.Logger
+}
+
+func NewCacheInvalidator(
+	notifier repository.ChangeNotifier,
+	repo repository.OrderRepository,
+	cache cache.OrderCache,
+	logger *zap.Logger,
+) *CacheInvalidator {
+	return &CacheInvalidator{
+		notifier: notifier,
+		repo:     repo,
+		cache:    cache,
+		logger:   logger,
+	}
+}
+
+// EnableNegativeCache - заказ, созданный другим инстансом, перестаёт считаться отсутствующим
+func (i *CacheInvalidator) EnableNegativeCache(n *cache.NegativeCache) {
+	i.negative = n
+}
+
+// Run слушает изменения до отмены ctx, переподписываясь при обрыве
+func (i *CacheInvalidator) Run(ctx context.Context) {
+	backoff := time.Second
+	subscribed := false
+
+	for {
+		changes, err := i.notifier.Listen(ctx)
+		if err != nil {
+			i.logger.Warn("failed to subscribe to order changes", zap.Error(err), zap.Duration("retry_in", backoff))
+		} else {
+			backoff = time.Second
+			// между подписками события не доставлялись
+			if subscribed {
+				i.apply(ctx, repository.OrderChange{Op: repository.OpReset})
+			}
+			subscribed = true
+			for change := range changes {
+				i.apply(ctx, change)
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, time.Minute)
+	}
+}
+
+func (i *CacheInvalidator) apply(ctx context.Context, change repository.OrderChange) {
+	if change.Op == repository.OpReset {
+		i.cache.Purge()
+		if i.negative != nil {
+			i.negative.Purge()
+		}
+		i.logger.Warn("order changes were lost, cache purged")
+		return
+	}
+
+	// только что созданный заказ в кэше уже актуален
+	if change.Op == repository.OpCreate {
+		if i.negative != nil {
+			i.negative.Remove(change.OrderUID)
+		}
+		return
+	}
+
+	// Peek не трогает статистику и порядок вытеснения
+	if _, ok := i.cache.Peek(change.OrderUID); !ok {
+		return
+	}
+
+	if change.Op == repository.OpDelete || change.Op == repository.OpEvict {
+		i.cache.Delete(change.OrderUID)
+		return
+	}
+
+	order, err := i.repo.GetOrder(ctx, change.OrderUID)
+	if err != nil {
+		// лучше промах, чем устаревшие данные
+		i.cache.Delete(change.OrderUID)
+		if !errors.Is(err, repository.ErrOrderNotFound) {
+			i.logger.Warn("failed to refresh cached order", zap.String("order_uid", change.OrderUID), zap.Error(err))
+		}
+		return
+	}
+
+	i.cache.Set(change.OrderUID, order)
+	i.logger.Debug("cached order refreshed", zap.String("order_uid", change.OrderUID), zap.String("op", change.Op))
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/cache"
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// fakeNotifier отдаёт тесту каждую подписку; закрытие канала - обрыв подписки
+type fakeNotifier struct {
+	subs chan chan repository.OrderChange
+	cur  chan repository.OrderChange
+}
+
+func newFakeNotifier() *fakeNotifier {
+	return &fakeNotifier{subs: make(chan chan repository.OrderChange)}
+}
+
+func (n *fakeNotifier) Listen(ctx context.Context) (<-chan repository.OrderChange, error) {
+	ch := make(chan repository.OrderChange)
+	select {
+	case n.subs <- ch:
+		return ch, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (n *fakeNotifier) subscribe(t *testing.T) {
+	t.Helper()
+
+	select {
+	case n.cur = <-n.subs:
+	case <-time.After(3 * time.Second):
+		t.Fatal("invalidator did not subscribe")
+	}
+}
+
+// send дожидается применения изменений: следующее чтение из канала
+// начинается только после apply предыдущего
+func (n *fakeNotifier) send(t *testing.T, changes ...repository.OrderChange) {
+	t.Helper()
+
+	changes = append(changes, repository.OrderChange{Op: repository.OpUpdate, OrderUID: "barrier"})
+	for _, change := range changes {
+		select {
+		case n.cur <- change:
+		case <-time.After(time.Second):
+			t.Fatal("invalidator did not take the change")
+		}
+	}
+}
+
+func runInvalidator(t *testing.T, inv *CacheInvalidator, n *fakeNotifier) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		inv.Run(ctx)
+		close(done)
+	}()
+
+	n.subscribe(t)
+	t.Cleanup(func() {
+		cancel()
+		close(n.cur)
+		<-done
+	})
+}
+
+func TestInvalidatorDeleteEvicts(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	seedOrder(t, repo, "a")
+	c := cache.NewLRUCache(10)
+	c.Set("a", &models.Order{OrderUID: "a"})
+
+	n := newFakeNotifier()
+	runInvalidator(t, NewCacheInvalidator(n, repo, c, zap.NewNop()), n)
+
+	n.send(t, repository.OrderChange{Op: repository.OpDelete, OrderUID: "a"})
+	if _, ok := c.Peek("a"); ok {
+		t.Fatal("deleted order is still cached")
+	}
+}
+
+func TestInvalidatorUpdateRefreshesCachedOnly(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	seedOrder(t, repo, "a")
+	seedOrder(t, repo, "b")
+	c := cache.NewLRUCache(10)
+	c.Set("a", &models.Order{OrderUID: "a", DateCreated: "stale"})
+
+	n := newFakeNotifier()
+	runInvalidator(t, NewCacheInvalidator(n, repo, c, zap.NewNop()), n)
+
+	n.send(t,
+		repository.OrderChange{Op: repository.OpUpdate, OrderUID: "a"},
+		repository.OrderChange{Op: repository.OpUpdate, OrderUID: "b"},
+	)
+
+	e, ok := c.Peek("a")
+	if !ok || e.Value.DateCreated == "stale" {
+		t.Fatalf("Peek(a) = %+v, %v; want refreshed order", e.Value, ok)
+	}
+	// чужой заказ в кэш не тянем
+	if _, ok := c.Peek("b"); ok {
+		t.Fatal("update cached an order that was not cached before")
+	}
+
+	// проверка наличия не считается ни попаданием, ни промахом
+	if st := c.Stats(); st.Hits != 0 || st.Misses != 0 {
+		t.Fatalf("stats = %+v, want no hits and misses", st)
+	}
+}
+
+func TestInvalidatorUpdateMissingOrderEvicts(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	c := cache.NewLRUCache(10)
+	c.Set("a", &models.Order{OrderUID: "a"})
+
+	n := newFakeNotifier()
+	runInvalidator(t, NewCacheInvalidator(n, repo, c, zap.NewNop()), n)
+
+	n.send(t, repository.OrderChange{Op: repository.OpUpdate, OrderUID: "a"})
+	if _, ok := c.Peek("a"); ok {
+		t.Fatal("order missing from the repo is still cached")
+	}
+}
+
+func TestInvalidatorCreateClearsNegative(t *testing.T) {
+	negative := cache.NewNegativeCache(10, time.Minute)
+	negative.Add("a", negative.Generation("a"))
+
+	n := newFakeNotifier()
+	inv := NewCacheInvalidator(n, repository.NewMemoryRepository(), cache.NewLRUCache(10), zap.NewNop())
+	inv.EnableNegativeCache(negative)
+	runInvalidator(t, inv, n)
+
+	n.send(t, repository.OrderChange{Op: repository.OpCreate, OrderUID: "a"})
+	if negative.Contains("a") {
+		t.Fatal("created order is still remembered as missing")
+	}
+}
+
+func TestInvalidatorResetPurges(t *testing.T) {
+	c := cache.NewLRUCache(10)
+	c.Set("a", &models.Order{OrderUID: "a"})
+	negative := cache.NewNegativeCache(10, time.Minute)
+	negative.Add("b", negative.Generation("b"))
+
+	n := newFakeNotifier()
+	inv := NewCacheInvalidator(n, repository.NewMemoryRepository(), c, zap.NewNop())
+	inv.EnableNegativeCache(negative)
+	runInvalidator(t, inv, n)
+
+	n.send(t, repository.OrderChange{Op: repository.OpReset})
+	if got := c.Stats().Len; got != 0 {
+		t.Fatalf("Len after reset = %d, want 0", got)
+	}
+	if negative.Contains("b") {
+		t.Fatal("negative cache survived reset")
+	}
+}
+
+func TestInvalidatorResubscribePurges(t *testing.T) {
+	c := cache.NewLRUCache(10)
+	n := newFakeNotifier()
+	runInvalidator(t, NewCacheInvalidator(n, repository.NewMemoryRepository(), c, zap.NewNop()), n)
+
+	// пока подписки нет, изменения теряются
+	c.Set("a", &models.Order{OrderUID: "a"})
+	close(n.cur)
+	n.subscribe(t)
+
+	n.send(t)
+	if _, ok := c.Peek("a"); ok {
+		t.Fatal("cache was not purged after resubscribe")
+	}
+}
+
+// listenReady сообщает, что подписка уже оформлена
+type listenReady struct {
+	repository.ChangeNotifier
+	ready chan struct{}
+}
+
+func (n *listenReady) Listen(ctx context.Context) (<-chan repository.OrderChange, error) {
+	changes, err := n.ChangeNotifier.Listen(ctx)
+	close(n.ready)
+	return changes, err
+}
+
+func TestInvalidatorMemoryRepoDelete(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	seedOrder(t, repo, "a")
+	c := cache.NewLRUCache(10)
+	c.Set("a", &models.Order{OrderUID: "a"})
+
+	n := &listenReady{ChangeNotifier: repo, ready: make(chan struct{})}
+	inv := NewCacheInvalidator(n, repo, c, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		inv.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	<-n.ready
+	if err := repo.DeleteOrder(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	waitEvicted(t, c, "a")
+}
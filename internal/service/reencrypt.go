@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ReencryptFunc переводит на текущий ключ пачку строк и возвращает число обновлённых
+type ReencryptFunc func(ctx context.Context, batchSize int) (int, error)
+
+// RunReencryption в фоне переводит строки table на текущий ключ:
+// пачками подряд, пока есть что перешифровывать, затем ждёт interval
+func RunReencryption(ctx context.Context, table string, reencrypt ReencryptFunc, interval time.Duration, batchSize int, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		total := 0
+		for ctx.Err() == nil {
+			n, err := reencrypt(ctx, batchSize)
+			if err != nil {
+				logger.Warn("reencryption failed", zap.String("table", table), zap.Error(err))
+				break
+			}
+
+			total += n
+			if n < batchSize {
+				break
+			}
+		}
+
+		if total > 0 {
+			logger.Info("rows reencrypted", zap.String("table", table), zap.Int("rows", total))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type SearchService struct {
+	repo   repository.SearchRepository
+	logger *zap.Logger
+}
+
+func NewSearchService(repo repository.SearchRepository, logger *zap.Logger) *SearchService {
+	return &SearchService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *SearchService) Search(ctx context.Context, q models.SearchQuery) ([]models.SearchResult, error) {
+	q.Text = strings.TrimSpace(q.Text)
+	q.Name = strings.TrimSpace(q.Name)
+	q.Phone = strings.TrimSpace(q.Phone)
+	q.Email = strings.TrimSpace(q.Email)
+
+	if q.Text == "" && q.Name == "" && q.Phone == "" && q.Email == "" {
+		return nil, fmt.Errorf("%w: one of q, name, phone or email is required", ErrInvalidArgument)
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidArgument)
+	}
+
+	if q.Limit == 0 {
+		q.Limit = defaultSearchLimit
+	}
+	if q.Limit < 0 || q.Limit > maxSearchLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidArgument, maxSearchLimit)
+	}
+
+	results, err := s.repo.SearchOrders(ctx, q)
+	if errors.Is(err, repository.ErrUnsupportedSearch) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArgument, err)
+	}
+	return results, err
+}
@@ -3,19 +3,41 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/torrentxok/order_service/internal/cache"
 	"github.com/torrentxok/order_service/internal/models"
 	"github.com/torrentxok/order_service/internal/repository"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 )
 
-var ErrOrderNotFound = errors.New("order not found")
+var (
+	ErrOrderNotFound   = errors.New("order not found")
+	ErrInvalidArgument = errors.New("invalid argument")
+
+	// ошибки хранилища, на которые вызывающий код реагирует по-разному
+	ErrUnavailable = repository.ErrUnavailable
+	ErrTimeout     = repository.ErrTimeout
+	ErrRetryable   = repository.ErrRetryable
+)
+
+// IsTemporary сообщает, имеет ли смысл повторить операцию позже
+func IsTemporary(err error) bool {
+	return errors.Is(err, ErrRetryable) || errors.Is(err, ErrUnavailable) || errors.Is(err, ErrTimeout)
+}
+
+// загрузка заказа общая для всех ждущих, поэтому не привязана к ctx первого из них
+const orderLoadTimeout = 10 * time.Second
 
 type OrderService struct {
-	repo   repository.OrderRepository
-	cache  cache.OrderCache
-	logger *zap.Logger
+	repo  repository.OrderRepository
+	cache cache.OrderCache
+	loads singleflight.Group
+	// nil - отсутствующие заказы не кэшируются
+	negative *cache.NegativeCache
+	logger   *zap.Logger
 }
 
 func NewOrderService(repo repository.OrderRepository, cache cache.OrderCache, logger *zap.Logger) *OrderService {
@@ -26,6 +48,11 @@ func NewOrderService(repo repository.OrderRepository, cache cache.OrderCache, lo
 	}
 }
 
+// EnableNegativeCache включает кэширование ErrOrderNotFound
+func (s *OrderService) EnableNegativeCache(n *cache.NegativeCache) {
+	s.negative = n
+}
+
 func (s *OrderService) CreateOrder(ctx context.Context, order *models.Order) error {
 	exists, err := s.repo.Exists(ctx, order.OrderUID)
 	if err != nil {
@@ -37,9 +64,16 @@ func (s *OrderService) CreateOrder(ctx context.Context, order *models.Order) err
 	}
 
 	if err := s.repo.CreateOrder(ctx, order); err != nil {
+		if errors.Is(err, repository.ErrOrderExists) {
+			s.logger.Info("order already exists", zap.String("order_uid", order.OrderUID))
+			return nil
+		}
 		return err
 	}
 
+	if s.negative != nil {
+		s.negative.Remove(order.OrderUID)
+	}
 	s.cache.Set(order.OrderUID, order)
 
 	return nil
@@ -66,15 +100,47 @@ func (s *OrderService) GetOrder(ctx context.Context, orderUID string) (*models.O
 		return order, nil
 	}
 
-	order, err := s.repo.GetOrder(ctx, orderUID)
-	if err != nil {
-		if errors.Is(err, repository.ErrOrderNotFound) {
-			return nil, ErrOrderNotFound
-		}
-		return nil, err
+	if s.negative != nil && s.negative.Contains(orderUID) {
+		return nil, ErrOrderNotFound
 	}
 
-	s.cache.Set(orderUID, order)
+	// одновременные промахи по одному ключу ждут одну загрузку из БД
+	ch := s.loads.DoChan(orderUID, func() (any, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), orderLoadTimeout)
+		defer cancel()
+
+		var gen uint64
+		if s.negative != nil {
+			gen = s.negative.Generation(orderUID)
+		}
+
+		order, err := s.repo.GetOrder(loadCtx, orderUID)
+		if err != nil {
+			if s.negative != nil && errors.Is(err, repository.ErrOrderNotFound) {
+				s.negative.Add(orderUID, gen)
+			}
+			return nil, err
+		}
 
-	return order, nil
+		s.cache.Set(orderUID, order)
+		return order, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		// загрузка продолжается для остальных ждущих, а этот запрос уже не ждёт
+		err := ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: %w", ErrTimeout, err)
+		}
+		return nil, err
+	case res := <-ch:
+		if res.Err != nil {
+			if errors.Is(res.Err, repository.ErrOrderNotFound) {
+				return nil, ErrOrderNotFound
+			}
+			return nil, res.Err
+		}
+		return res.Val.(*models.Order), nil
+	}
 }
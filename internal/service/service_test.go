@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/cache"
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// blockingRepo считает обращения к GetOrder и держит их до release
+type blockingRepo struct {
+	*repository.MemoryRepo
+	calls   atomic.Int32
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newBlockingRepo() *blockingRepo {
+	return &blockingRepo{
+		MemoryRepo: repository.NewMemoryRepository(),
+		entered:    make(chan struct{}, 1),
+		release:    make(chan struct{}),
+	}
+}
+
+func (r *blockingRepo) GetOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	r.calls.Add(1)
+	select {
+	case r.entered <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-r.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return r.MemoryRepo.GetOrder(ctx, orderUID)
+}
+
+// missCounter отмечает промахи, чтобы знать, что все читатели дошли до загрузки
+type missCounter struct {
+	cache.OrderCache
+	misses sync.WaitGroup
+}
+
+func (c *missCounter) Get(key string) (*models.Order, bool) {
+	order, ok := c.OrderCache.Get(key)
+	if !ok {
+		c.misses.Done()
+	}
+	return order, ok
+}
+
+func seedOrder(t *testing.T, repo *repository.MemoryRepo, uid string) {
+	t.Helper()
+
+	o := &models.Order{OrderUID: uid, DateCreated: time.Now().UTC().Format(time.RFC3339)}
+	if err := repo.CreateOrder(context.Background(), o); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetOrderCoalescesConcurrentMisses(t *testing.T) {
+	const readers = 100
+
+	repo := newBlockingRepo()
+	seedOrder(t, repo.MemoryRepo, "a")
+
+	c := &missCounter{OrderCache: cache.NewLRUCache(10)}
+	c.misses.Add(readers)
+	svc := NewOrderService(repo, c, zap.NewNop())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, readers)
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order, err := svc.GetOrder(context.Background(), "a")
+			if err == nil && order.OrderUID != "a" {
+				err = errors.New("wrong order " + order.OrderUID)
+			}
+			errs <- err
+		}()
+	}
+
+	// все промахнулись; даём им встать в ожидание одной загрузки
+	c.misses.Wait()
+	time.Sleep(20 * time.Millisecond)
+	close(repo.release)
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("GetOrder: %v", err)
+		}
+	}
+
+	if n := repo.calls.Load(); n != 1 {
+		t.Fatalf("repo.GetOrder called %d times, want 1", n)
+	}
+}
+
+func TestGetOrderCallerCancel(t *testing.T) {
+	repo := newBlockingRepo()
+	seedOrder(t, repo.MemoryRepo, "a")
+
+	c := cache.NewLRUCache(10)
+	svc := NewOrderService(repo, c, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.GetOrder(ctx, "a")
+		done <- err
+	}()
+
+	<-repo.entered
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("GetOrder error = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetOrder did not return after cancel")
+	}
+
+	// загрузка не привязана к отменённому запросу и доходит до кэша
+	close(repo.release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok := c.Peek("a"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("order was not cached after the canceled caller")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGetOrderDeadlineIsTimeout(t *testing.T) {
+	repo := newBlockingRepo()
+	defer close(repo.release)
+	seedOrder(t, repo.MemoryRepo, "a")
+
+	svc := NewOrderService(repo, cache.NewLRUCache(10), zap.NewNop())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := svc.GetOrder(ctx, "a")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("GetOrder error = %v, want ErrTimeout", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetOrder error = %v, want it to wrap context.DeadlineExceeded", err)
+	}
+}
+
+func TestGetOrderNotFound(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	svc := NewOrderService(repo, cache.NewLRUCache(10), zap.NewNop())
+
+	_, err := svc.GetOrder(context.Background(), "missing")
+	if !errors.Is(err, ErrOrderNotFound) {
+		t.Fatalf("GetOrder error = %v, want ErrOrderNotFound", err)
+	}
+}
+
+func TestGetOrderAfterCreateWithNegativeCache(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	c := cache.NewLRUCache(10)
+	svc := NewOrderService(repo, c, zap.NewNop())
+	svc.EnableNegativeCache(cache.NewNegativeCache(10, time.Minute))
+
+	if _, err := svc.GetOrder(context.Background(), "a"); !errors.Is(err, ErrOrderNotFound) {
+		t.Fatalf("GetOrder before create error = %v, want ErrOrderNotFound", err)
+	}
+
+	o := &models.Order{OrderUID: "a", DateCreated: time.Now().UTC().Format(time.RFC3339)}
+	if err := svc.CreateOrder(context.Background(), o); err != nil {
+		t.Fatal(err)
+	}
+	// чтение идёт мимо кэша заказов, через проверку отсутствующих
+	c.Purge()
+
+	order, err := svc.GetOrder(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("GetOrder after create: %v", err)
+	}
+	if order.OrderUID != "a" {
+		t.Fatalf("GetOrder = %s, want a", order.OrderUID)
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/torrentxok/order_service/internal/models"
+	"github.com/torrentxok/order_service/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultStatsPeriod = 30 * 24 * time.Hour
+	maxStatsPeriod     = 366 * 24 * time.Hour
+	defaultStatsLimit  = 10
+	maxStatsLimit      = 100
+)
+
+type StatsService struct {
+	repo   repository.AnalyticsRepository
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewStatsService(repo repository.AnalyticsRepository, logger *zap.Logger) *StatsService {
+	return &StatsService{
+		repo:   repo,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+func (s *StatsService) Revenue(ctx context.Context, f models.StatsFilter) ([]models.RevenuePoint, error) {
+	if err := s.normalize(&f); err != nil {
+		return nil, err
+	}
+	return s.repo.RevenueByCurrency(ctx, f)
+}
+
+func (s *StatsService) Basket(ctx context.Context, f models.StatsFilter) ([]models.BasketPoint, error) {
+	if err := s.normalize(&f); err != nil {
+		return nil, err
+	}
+	return s.repo.BasketSize(ctx, f)
+}
+
+func (s *StatsService) TopBrands(ctx context.Context, f models.StatsFilter) ([]models.BrandStat, error) {
+	if err := s.normalize(&f); err != nil {
+		return nil, err
+	}
+	return s.repo.TopBrands(ctx, f)
+}
+
+func (s *StatsService) DeliveryServices(ctx context.Context, f models.StatsFilter) ([]models.BreakdownStat, error) {
+	if err := s.normalize(&f); err != nil {
+		return nil, err
+	}
+	return s.repo.OrdersByDeliveryService(ctx, f)
+}
+
+func (s *StatsService) Payments(ctx context.Context, f models.StatsFilter, by string) ([]models.BreakdownStat, error) {
+	if err := s.normalize(&f); err != nil {
+		return nil, err
+	}
+
+	if by == "" {
+		by = "provider"
+	}
+	if by != "provider" && by != "bank" && by != "currency" {
+		return nil, fmt.Errorf("%w: by must be provider, bank or currency", ErrInvalidArgument)
+	}
+
+	return s.repo.PaymentBreakdown(ctx, f, by)
+}
+
+// normalize подставляет значения по умолчанию и ограничивает тяжёлые запросы
+func (s *StatsService) normalize(f *models.StatsFilter) error {
+	if f.To.IsZero() {
+		f.To = s.now()
+	}
+	if f.From.IsZero() {
+		f.From = f.To.Add(-defaultStatsPeriod)
+	}
+	if !f.From.Before(f.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidArgument)
+	}
+	if f.To.Sub(f.From) > maxStatsPeriod {
+		return fmt.Errorf("%w: period must not exceed %d days", ErrInvalidArgument, int(maxStatsPeriod.Hours()/24))
+	}
+
+	switch f.GroupBy {
+	case "":
+		f.GroupBy = "day"
+	case "day", "week", "month":
+	default:
+		return fmt.Errorf("%w: group_by must be day, week or month", ErrInvalidArgument)
+	}
+
+	if f.Limit == 0 {
+		f.Limit = defaultStatsLimit
+	}
+	if f.Limit < 0 || f.Limit > maxStatsLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidArgument, maxStatsLimit)
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package transfer
+
+import (
+	"strconv"
+
+	"github.com/torrentxok/order_service/internal/models"
+)
+
+// FlatRow - заказ, развёрнутый до строки на каждый товар (CSV, Parquet)
+type FlatRow struct {
+	OrderUID        string `parquet:"order_uid"`
+	TrackNumber     string `parquet:"track_number"`
+	Entry           string `parquet:"entry"`
+	Locale          string `parquet:"locale"`
+	InternalSig     string `parquet:"internal_signature"`
+	CustomerID      string `parquet:"customer_id"`
+	DeliveryService string `parquet:"delivery_service"`
+	ShardKey        string `parquet:"shardkey"`
+	SmID            int64  `parquet:"sm_id"`
+	DateCreated     string `parquet:"date_created"`
+	OofShard        string `parquet:"oof_shard"`
+
+	DeliveryName    string `parquet:"delivery_name"`
+	DeliveryPhone   string `parquet:"delivery_phone"`
+	DeliveryZip     string `parquet:"delivery_zip"`
+	DeliveryCity    string `parquet:"delivery_city"`
+	DeliveryAddress string `parquet:"delivery_address"`
+	DeliveryRegion  string `parquet:"delivery_region"`
+	DeliveryEmail   string `parquet:"delivery_email"`
+
+	PaymentTransaction  string `parquet:"payment_transaction"`
+	PaymentRequestID    string `parquet:"payment_request_id"`
+	PaymentCurrency     string `parquet:"payment_currency"`
+	PaymentProvider     string `parquet:"payment_provider"`
+	PaymentAmount       int64  `parquet:"payment_amount"`
+	PaymentDT           int64  `parquet:"payment_dt"`
+	PaymentBank         string `parquet:"payment_bank"`
+	PaymentDeliveryCost int64  `parquet:"payment_delivery_cost"`
+	PaymentGoodsTotal   int64  `parquet:"payment_goods_total"`
+	PaymentCustomFee    int64  `parquet:"payment_custom_fee"`
+
+	ItemChrtID      int64  `parquet:"item_chrt_id"`
+	ItemTrackNumber string `parquet:"item_track_number"`
+	ItemPrice       int64  `parquet:"item_price"`
+	ItemRid         string `parquet:"item_rid"`
+	ItemName        string `parquet:"item_name"`
+	ItemSale        int64  `parquet:"item_sale"`
+	ItemSize        string `parquet:"item_size"`
+	ItemTotalPrice  int64  `parquet:"item_total_price"`
+	ItemNmID        int64  `parquet:"item_nm_id"`
+	ItemBrand       string `parquet:"item_brand"`
+	ItemStatus      int64  `parquet:"item_status"`
+}
+
+var csvHeader = []string{
+	"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id",
+	"delivery_service", "shardkey", "sm_id", "date_created", "oof_shard",
+	"delivery_name", "delivery_phone", "delivery_zip", "delivery_city",
+	"delivery_address", "delivery_region", "delivery_email",
+	"payment_transaction", "payment_request_id", "payment_currency", "payment_provider",
+	"payment_amount", "payment_dt", "payment_bank", "payment_delivery_cost",
+	"payment_goods_total", "payment_custom_fee",
+	"item_chrt_id", "item_track_number", "item_price", "item_rid", "item_name",
+	"item_sale", "item_size", "item_total_price", "item_nm_id", "item_brand", "item_status",
+}
+
+// flatten отдаёт хотя бы одну строку, даже если у заказа нет товаров
+func flatten(o *models.Order) []FlatRow {
+	base := FlatRow{
+		OrderUID:        o.OrderUID,
+		TrackNumber:     o.TrackNumber,
+		Entry:           o.Entry,
+		Locale:          o.Locale,
+		InternalSig:     o.InternalSig,
+		CustomerID:      o.CustomerID,
+		DeliveryService: o.DeliveryService,
+		ShardKey:        o.ShardKey,
+		SmID:            int64(o.SmID),
+		DateCreated:     o.DateCreated,
+		OofShard:        o.OofShard,
+
+		DeliveryName:    o.Delivery.Name,
+		DeliveryPhone:   o.Delivery.Phone,
+		DeliveryZip:     o.Delivery.Zip,
+		DeliveryCity:    o.Delivery.City,
+		DeliveryAddress: o.Delivery.Address,
+		DeliveryRegion:  o.Delivery.Region,
+		DeliveryEmail:   o.Delivery.Email,
+
+		PaymentTransaction:  o.Payment.Transaction,
+		PaymentRequestID:    o.Payment.RequestID,
+		PaymentCurrency:     o.Payment.Currency,
+		PaymentProvider:     o.Payment.Provider,
+		PaymentAmount:       int64(o.Payment.Amount),
+		PaymentDT:           o.Payment.PaymentDT,
+		PaymentBank:         o.Payment.Bank,
+		PaymentDeliveryCost: int64(o.Payment.DeliveryCost),
+		PaymentGoodsTotal:   int64(o.Payment.GoodsTotal),
+		PaymentCustomFee:    int64(o.Payment.CustomFee),
+	}
+
+	if len(o.Items) == 0 {
+		return []FlatRow{base}
+	}
+
+	rows := make([]FlatRow, 0, len(o.Items))
+	for _, it := range o.Items {
+		row := base
+		row.ItemChrtID = int64(it.ChrtID)
+		row.ItemTrackNumber = it.TrackNumber
+		row.ItemPrice = int64(it.Price)
+		row.ItemRid = it.Rid
+		row.ItemName = it.Name
+		row.ItemSale = int64(it.Sale)
+		row.ItemSize = it.Size
+		row.ItemTotalPrice = int64(it.TotalPrice)
+		row.ItemNmID = int64(it.NmID)
+		row.ItemBrand = it.Brand
+		row.ItemStatus = int64(it.Status)
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func (r *FlatRow) record() []string {
+	i := func(v int64) string { return strconv.FormatInt(v, 10) }
+
+	return []string{
+		r.OrderUID, r.TrackNumber, r.Entry, r.Locale, r.InternalSig, r.CustomerID,
+		r.DeliveryService, r.ShardKey, i(r.SmID), r.DateCreated, r.OofShard,
+		r.DeliveryName, r.DeliveryPhone, r.DeliveryZip, r.DeliveryCity,
+		r.DeliveryAddress, r.DeliveryRegion, r.DeliveryEmail,
+		r.PaymentTransaction, r.PaymentRequestID, r.PaymentCurrency, r.PaymentProvider,
+		i(r.PaymentAmount), i(r.PaymentDT), r.PaymentBank, i(r.PaymentDeliveryCost),
+		i(r.PaymentGoodsTotal), i(r.PaymentCustomFee),
+		i(r.ItemChrtID), r.ItemTrackNumber, i(r.ItemPrice), r.ItemRid, r.ItemName,
+		i(r.ItemSale), r.ItemSize, i(r.ItemTotalPrice), i(r.ItemNmID), r.ItemBrand, i(r.ItemStatus),
+	}
+}
+
+// orderFromRecord собирает шапку заказа из первой строки и товар из каждой
+func orderFromRecord(rec []string) (*models.Order, models.Item, error) {
+	var err error
+	num := func(s string) int {
+		if err != nil {
+			return 0
+		}
+		var v int
+		v, err = strconv.Atoi(s)
+		return v
+	}
+
+	o := &models.Order{
+		OrderUID:        rec[0],
+		TrackNumber:     rec[1],
+		Entry:           rec[2],
+		Locale:          rec[3],
+		InternalSig:     rec[4],
+		CustomerID:      rec[5],
+		DeliveryService: rec[6],
+		ShardKey:        rec[7],
+		SmID:            num(rec[8]),
+		DateCreated:     rec[9],
+		OofShard:        rec[10],
+		Delivery: models.Delivery{
+			Name:    rec[11],
+			Phone:   rec[12],
+			Zip:     rec[13],
+			City:    rec[14],
+			Address: rec[15],
+			Region:  rec[16],
+			Email:   rec[17],
+		},
+		Payment: models.Payment{
+			Transaction:  rec[18],
+			RequestID:    rec[19],
+			Currency:     rec[20],
+			Provider:     rec[21],
+			Amount:       num(rec[22]),
+			Bank:         rec[24],
+			DeliveryCost: num(rec[25]),
+			GoodsTotal:   num(rec[26]),
+			CustomFee:    num(rec[27]),
+		},
+	}
+
+	if err == nil {
+		o.Payment.PaymentDT, err = strconv.ParseInt(rec[23], 10, 64)
+	}
+
+	item := models.Item{
+		ChrtID:      num(rec[28]),
+		TrackNumber: rec[29],
+		Price:       num(rec[30]),
+		Rid:         rec[31],
+		Name:        rec[32],
+		Sale:        num(rec[33]),
+		Size:        rec[34],
+		TotalPrice:  num(rec[35]),
+		NmID:        num(rec[36]),
+		Brand:       rec[37],
+		Status:      num(rec[38]),
+	}
+
+	return o, item, err
+}
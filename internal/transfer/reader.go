@@ -0,0 +1,159 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/torrentxok/order_service/internal/models"
+)
+
+// ErrRowsNotAdjacent - строки заказа в csv разбросаны по файлу: повторная группа
+// не склеивается с уже отданной и пропускается целиком
+var ErrRowsNotAdjacent = errors.New("order rows are not adjacent")
+
+// RecordError - запись не разобрана, но чтение можно продолжать
+type RecordError struct {
+	Line int
+	Err  error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+type Reader interface {
+	// Read возвращает io.EOF по окончании данных
+	Read() (*models.Order, error)
+}
+
+// NewReader поддерживает ndjson и csv; parquet предназначен только для выгрузки в аналитику
+func NewReader(format string, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatNDJSON:
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		return &ndjsonReader{sc: sc}, nil
+	case FormatCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = len(csvHeader)
+
+		header, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("read csv header: %w", err)
+		}
+		if !slices.Equal(header, csvHeader) {
+			return nil, errors.New("unexpected csv header")
+		}
+
+		return &csvReader{cr: cr, line: 1, seen: make(map[string]struct{})}, nil
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+type ndjsonReader struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+func (r *ndjsonReader) Read() (*models.Order, error) {
+	for r.sc.Scan() {
+		r.line++
+
+		data := r.sc.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+
+		var o models.Order
+		if err := json.Unmarshal(data, &o); err != nil {
+			return nil, &RecordError{Line: r.line, Err: err}
+		}
+		return &o, nil
+	}
+
+	if err := r.sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// csvReader собирает подряд идущие строки с одним order_uid в один заказ
+type csvReader struct {
+	cr      *csv.Reader
+	line    int
+	pending *models.Order
+	pendErr error
+	eof     bool
+	// order_uid уже начатых групп
+	seen map[string]struct{}
+}
+
+func (r *csvReader) Read() (*models.Order, error) {
+	for !r.eof {
+		rec, err := r.cr.Read()
+		if errors.Is(err, io.EOF) {
+			r.eof = true
+			break
+		}
+		r.line++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, &RecordError{Line: r.line, Err: err}
+			}
+			return nil, err
+		}
+
+		o, item, convErr := orderFromRecord(rec)
+		if convErr != nil {
+			convErr = &RecordError{Line: r.line, Err: convErr}
+		}
+
+		if r.pending != nil && r.pending.OrderUID == o.OrderUID {
+			r.addItem(r.pending, item)
+			if r.pendErr == nil {
+				r.pendErr = convErr
+			}
+			continue
+		}
+
+		if _, ok := r.seen[o.OrderUID]; ok && convErr == nil {
+			convErr = &RecordError{Line: r.line, Err: fmt.Errorf("%w: %s", ErrRowsNotAdjacent, o.OrderUID)}
+		}
+		r.seen[o.OrderUID] = struct{}{}
+
+		done, doneErr := r.pending, r.pendErr
+		r.pending, r.pendErr = o, convErr
+		r.addItem(o, item)
+
+		if done != nil {
+			return done, doneErr
+		}
+	}
+
+	if r.pending != nil {
+		done, doneErr := r.pending, r.pendErr
+		r.pending, r.pendErr = nil, nil
+		return done, doneErr
+	}
+
+	return nil, io.EOF
+}
+
+// строка заказа без товаров выгружается с пустыми полями товара
+func (r *csvReader) addItem(o *models.Order, item models.Item) {
+	if item == (models.Item{}) {
+		return
+	}
+	o.Items = append(o.Items, item)
+}
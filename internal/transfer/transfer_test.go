@@ -0,0 +1,177 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/torrentxok/order_service/internal/models"
+)
+
+func testOrders() []*models.Order {
+	order := func(uid string, items int) *models.Order {
+		o := &models.Order{
+			OrderUID:        uid,
+			TrackNumber:     "WBILMTESTTRACK",
+			Entry:           "WBIL",
+			Locale:          "en",
+			CustomerID:      "test",
+			DeliveryService: "meest",
+			ShardKey:        "9",
+			SmID:            99,
+			DateCreated:     "2021-11-26T06:22:19Z",
+			OofShard:        "1",
+			Delivery: models.Delivery{
+				Name:    "Test Testov",
+				Phone:   "+9720000000",
+				Zip:     "2639809",
+				City:    "Kiryat Mozkin",
+				Address: "Ploshad Mira 15",
+				Region:  "Kraiot",
+				Email:   "test@gmail.com",
+			},
+			Payment: models.Payment{
+				Transaction:  uid,
+				Currency:     "USD",
+				Provider:     "wbpay",
+				Amount:       1817,
+				PaymentDT:    1637907727,
+				Bank:         "alpha",
+				DeliveryCost: 1500,
+				GoodsTotal:   317,
+			},
+		}
+		for i := range items {
+			o.Items = append(o.Items, models.Item{
+				ChrtID:      9934930 + i,
+				TrackNumber: "WBILMTESTTRACK",
+				Price:       453,
+				Rid:         "ab4219087a764ae0btest",
+				Name:        "Mascaras, \"waterproof\"",
+				Sale:        30,
+				Size:        "0",
+				TotalPrice:  317,
+				NmID:        2389212 + i,
+				Brand:       "Vivienne Sabo",
+				Status:      202,
+			})
+		}
+		return o
+	}
+
+	return []*models.Order{order("a", 3), order("b", 1), order("c", 0), order("d", 2)}
+}
+
+func writeAll(t *testing.T, format string, orders []*models.Order) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range orders {
+		if err := w.Write(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readAll(t *testing.T, format string, data []byte) ([]*models.Order, []error) {
+	t.Helper()
+
+	r, err := NewReader(format, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		orders []*models.Order
+		errs   []error
+	)
+	for {
+		o, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return orders, errs
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		orders = append(orders, o)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatNDJSON, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			want := testOrders()
+
+			got, errs := readAll(t, format, writeAll(t, format, want))
+			if len(errs) > 0 {
+				t.Fatalf("read errors: %v", errs)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("read back %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// parquet не импортируется, проверяем, что строки товаров выгружены полностью
+func TestParquetRoundTrip(t *testing.T) {
+	orders := testOrders()
+	data := writeAll(t, FormatParquet, orders)
+
+	var want []FlatRow
+	for _, o := range orders {
+		want = append(want, flatten(o)...)
+	}
+
+	r := parquet.NewGenericReader[FlatRow](bytes.NewReader(data))
+	defer r.Close()
+
+	got := make([]FlatRow, r.NumRows())
+	n, err := r.Read(got)
+	if err != nil && !errors.Is(err, io.EOF) {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got[:n], want) {
+		t.Fatalf("read back %d rows %+v, want %d rows %+v", n, got[:n], len(want), want)
+	}
+}
+
+func TestCSVRowsNotAdjacent(t *testing.T) {
+	orders := testOrders()
+	data := writeAll(t, FormatCSV, orders[:2])
+
+	// строки заказа a разнесены: a, a, b, a
+	lines := strings.SplitAfter(string(data), "\n")
+	header, a, b := lines[0], lines[1:4], lines[4]
+	shuffled := header + a[0] + a[1] + b + a[2]
+
+	got, errs := readAll(t, FormatCSV, []byte(shuffled))
+
+	if len(errs) != 1 || !errors.Is(errs[0], ErrRowsNotAdjacent) {
+		t.Fatalf("errors = %v, want one ErrRowsNotAdjacent", errs)
+	}
+	var recErr *RecordError
+	if !errors.As(errs[0], &recErr) || recErr.Line != 5 {
+		t.Fatalf("error = %v, want RecordError at line 5", errs[0])
+	}
+
+	if len(got) != 2 || got[0].OrderUID != "a" || got[1].OrderUID != "b" {
+		t.Fatalf("read back %d orders, want a and b", len(got))
+	}
+	if len(got[0].Items) != 2 {
+		t.Fatalf("order a has %d items, want the 2 adjacent ones", len(got[0].Items))
+	}
+}
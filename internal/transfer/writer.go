@@ -0,0 +1,86 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/torrentxok/order_service/internal/models"
+)
+
+const (
+	FormatNDJSON  = "ndjson"
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+)
+
+type Writer interface {
+	Write(o *models.Order) error
+	// Close дописывает буферы и футер, сам io.Writer не закрывает
+	Close() error
+}
+
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatNDJSON:
+		bw := bufio.NewWriter(w)
+		return &ndjsonWriter{bw: bw, enc: json.NewEncoder(bw)}, nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return &csvWriter{cw: cw}, nil
+	case FormatParquet:
+		return &parquetWriter{pw: parquet.NewGenericWriter[FlatRow](w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+type ndjsonWriter struct {
+	bw  *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(o *models.Order) error {
+	return w.enc.Encode(o)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.bw.Flush()
+}
+
+type csvWriter struct {
+	cw *csv.Writer
+}
+
+func (w *csvWriter) Write(o *models.Order) error {
+	for _, row := range flatten(o) {
+		if err := w.cw.Write(row.record()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *csvWriter) Close() error {
+	w.cw.Flush()
+	return w.cw.Error()
+}
+
+type parquetWriter struct {
+	pw *parquet.GenericWriter[FlatRow]
+}
+
+func (w *parquetWriter) Write(o *models.Order) error {
+	_, err := w.pw.Write(flatten(o))
+	return err
+}
+
+func (w *parquetWriter) Close() error {
+	return w.pw.Close()
+}